@@ -0,0 +1,63 @@
+package logos
+
+import (
+	"errors"
+	"net/http"
+)
+
+// AdminHandler returns an http.Handler exposing runtime operations:
+//
+//	GET /dump?appender=NAME      writes the entries kept by the appender
+//	POST /rotate[?appender=NAME] rotates the appender, or every appender
+//	                             supporting rotation; repeat the parameter
+//	                             to rotate several
+//
+// Mount it with http.StripPrefix to serve it under a sub path.
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dump", handleDump)
+	mux.HandleFunc("/rotate", handleRotate)
+	return mux
+}
+
+func handleDump(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("appender")
+	if name == "" {
+		http.Error(w, "query parameter 'appender' is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := Dump(name, w); err != nil {
+		writeAdminError(w, err)
+	}
+}
+
+func handleRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := Rotate(r.URL.Query()["appender"]...); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrAppenderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrDumpNotSupported), errors.Is(err, ErrRotateNotSupported):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
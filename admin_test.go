@@ -0,0 +1,150 @@
+package logos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler_dump(t *testing.T) {
+	const newConfig = `
+appenders:
+  console:
+    - name: CONSOLE
+      target: discard
+      encoder:
+        console:
+  memory:
+    - name: MEMORY
+      size: 2
+      encoder:
+        json:
+          time_key: ""
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - CONSOLE
+      - MEMORY
+`
+	err := InitWithConfigContent(newConfig)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = InitWithConfigContent(config.DefaultConfig)
+	})
+
+	log := New("admin")
+	log.Info("first")
+	log.Info("second")
+	log.Info("third")
+
+	var out strings.Builder
+	assert.NoError(t, Dump("MEMORY", &out))
+	assert.Equal(t, `{"level":"info","logger":"admin","msg":"second"}
+{"level":"info","logger":"admin","msg":"third"}
+`, out.String())
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"dump", "/dump?appender=MEMORY", http.StatusOK},
+		{"no appender", "/dump", http.StatusBadRequest},
+		{"unknown appender", "/dump?appender=UNKNOWN", http.StatusNotFound},
+		{"not supported", "/dump?appender=CONSOLE", http.StatusBadRequest},
+	}
+
+	handler := AdminHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, out.String(), rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminHandler_rotate(t *testing.T) {
+	dir := t.TempDir()
+	newConfig := `
+appenders:
+  console:
+    - name: CONSOLE
+      target: discard
+      encoder:
+        console:
+  rolling_file:
+    - name: ROLLING
+      file_name: ` + filepath.Join(dir, "app.log") + `
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - CONSOLE
+      - ROLLING
+`
+	err := InitWithConfigContent(newConfig)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = InitWithConfigContent(config.DefaultConfig)
+	})
+
+	backups := func() int {
+		matches, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+		return len(matches)
+	}
+
+	log := New("rotate")
+	log.Info("first")
+	assert.NoError(t, Rotate("ROLLING"))
+	assert.Equal(t, 1, backups())
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		status int
+	}{
+		{"rotate", http.MethodPost, "/rotate?appender=ROLLING", http.StatusNoContent},
+		{"rotate all", http.MethodPost, "/rotate", http.StatusNoContent},
+		{"unknown appender", http.MethodPost, "/rotate?appender=UNKNOWN", http.StatusNotFound},
+		{"not supported", http.MethodPost, "/rotate?appender=CONSOLE", http.StatusBadRequest},
+		{"method", http.MethodGet, "/rotate", http.StatusMethodNotAllowed},
+	}
+
+	handler := AdminHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+	assert.Equal(t, 3, backups())
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	stop := RotateOnSignal()
+	defer stop()
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip(err)
+	}
+	for i := 0; i < 100 && backups() < 4; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 4, backups())
+}
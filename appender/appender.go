@@ -3,11 +3,27 @@ package appender
 import (
 	"fmt"
 	"github.com/khorevaa/logos/appender/console"
+	"github.com/khorevaa/logos/appender/elasticsearch"
 	"github.com/khorevaa/logos/appender/file"
+	"github.com/khorevaa/logos/appender/fluent"
+	"github.com/khorevaa/logos/appender/gelfhttp"
+	"github.com/khorevaa/logos/appender/gelftcp"
 	"github.com/khorevaa/logos/appender/gelfudp"
+	"github.com/khorevaa/logos/appender/journald"
+	"github.com/khorevaa/logos/appender/loki"
+	"github.com/khorevaa/logos/appender/memory"
+	"github.com/khorevaa/logos/appender/otlp"
 	"github.com/khorevaa/logos/appender/rollingfile"
+	"github.com/khorevaa/logos/appender/routing"
+	"github.com/khorevaa/logos/appender/smtp"
+	"github.com/khorevaa/logos/appender/socket"
+	"github.com/khorevaa/logos/appender/splunk"
+	"github.com/khorevaa/logos/appender/sql"
+	"github.com/khorevaa/logos/appender/syslog"
+	"github.com/khorevaa/logos/appender/webhook"
 	"github.com/khorevaa/logos/internal/common"
 	"go.uber.org/zap/zapcore"
+	"io"
 )
 
 var (
@@ -18,6 +34,28 @@ var (
 type WriterFactory func(config *common.Config) (zapcore.WriteSyncer, error)
 type EncoderFactory func(*common.Config) (zapcore.Encoder, error)
 
+// Dumper is implemented by writers keeping recent entries that can be read back.
+type Dumper interface {
+	Dump(w io.Writer) error
+}
+
+// Rotator is implemented by writers that can start a new file on demand.
+type Rotator interface {
+	Rotate() error
+}
+
+// ColorForcer is implemented by encoders that can be forced to write colors
+// to outputs that are not terminals.
+type ColorForcer interface {
+	ColorsForced() bool
+}
+
+// ColorKeeper is implemented by writers removing the colors from outputs
+// that are not terminals, KeepColors makes them keep the colors.
+type ColorKeeper interface {
+	KeepColors()
+}
+
 type Appender struct {
 	Writer  zapcore.WriteSyncer
 	Encoder zapcore.Encoder
@@ -28,6 +66,32 @@ func init() {
 	RegisterWriterType("file", file.New)
 	RegisterWriterType("rolling_file", rollingfile.New)
 	RegisterWriterType("gelf_udp", gelfudp.New)
+	RegisterWriterType("gelf_tcp", gelftcp.New)
+	RegisterWriterType("gelf_http", gelfhttp.New)
+	RegisterWriterType("memory", memory.New)
+	RegisterWriterType("syslog", syslog.New)
+	RegisterWriterType("socket", socket.New)
+	RegisterWriterType("loki", loki.New)
+	RegisterWriterType("elasticsearch", elasticsearch.New)
+	RegisterWriterType("splunk_hec", splunk.New)
+	RegisterWriterType("otlp", otlp.New)
+	RegisterWriterType("fluent_forward", fluent.New)
+	RegisterWriterType("journald", journald.New)
+	RegisterWriterType("webhook", webhook.New)
+	RegisterWriterType("smtp", smtp.New)
+	RegisterWriterType("sql", sql.New)
+	RegisterWriterType("routing", newRoutingWriter)
+}
+
+// newRoutingWriter creates the targets of a routing writer with the
+// registered writer types.
+func newRoutingWriter(config *common.Config) (zapcore.WriteSyncer, error) {
+	return routing.New(config, func(writerType string) routing.Factory {
+		if f := writers[writerType]; f != nil {
+			return routing.Factory(f)
+		}
+		return nil
+	})
 }
 
 func CreateAppender(writerType string, config *common.Config) (*Appender, error) {
@@ -47,6 +111,12 @@ func CreateAppender(writerType string, config *common.Config) (*Appender, error)
 	if err != nil {
 		return nil, err
 	}
+	// the colors forced on the encoder are not removed by the writer
+	if f, ok := e.(ColorForcer); ok && f.ColorsForced() {
+		if k, ok := w.(ColorKeeper); ok {
+			k.KeepColors()
+		}
+	}
 	return &Appender{w, e}, nil
 }
 
@@ -13,11 +13,23 @@ import (
 type Console struct {
 	zapcore.WriteSyncer
 	colorable bool
+
+	file    *os.File
+	noColor bool
 }
 
 type Config struct {
-	Target  `logos-config:"target" logos-validate:"required,logos.oneof=stderr stdout discard"`
+	Target  `logos-config:"target" logos-validate:"required,logos.oneof=stderr stdout split discard"`
 	NoColor bool `logos-config:"no_color"`
+
+	// ForceColors keeps the colors when the target is not a terminal or the
+	// environment sets NO_COLOR or TERM=dumb. Otherwise the colors written
+	// by the encoder are removed from such targets.
+	ForceColors bool `logos-config:"force_colors"`
+
+	// SplitLevel is the lowest level written to stderr by the split target,
+	// the lower levels are written to stdout.
+	SplitLevel string `logos-config:"split_level"`
 }
 
 type Target = string
@@ -26,11 +38,13 @@ const (
 	Discard Target = "discard"
 	Stdout  Target = "stdout"
 	Stderr  Target = "stderr"
+	Split   Target = "split"
 )
 
 var (
 	defaultConfig = Config{
-		Target: Stdout,
+		Target:     Stdout,
+		SplitLevel: "warn",
 	}
 )
 
@@ -48,8 +62,14 @@ func New(v *common.Config) (zapcore.WriteSyncer, error) {
 		return NewConsole(cfg, os.Stdout), nil
 	case Stderr:
 		return NewConsole(cfg, os.Stderr), nil
+	case Split:
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(cfg.SplitLevel)); err != nil {
+			return nil, fmt.Errorf("split_level: %w", err)
+		}
+		return NewSplit(cfg, level, os.Stdout, os.Stderr), nil
 	case Discard:
-		return &Console{zapcore.AddSync(ioutil.Discard), false}, nil
+		return &Console{WriteSyncer: zapcore.AddSync(ioutil.Discard)}, nil
 	default:
 		return nil, fmt.Errorf("unknown target %q", cfg.Target)
 	}
@@ -57,11 +77,77 @@ func New(v *common.Config) (zapcore.WriteSyncer, error) {
 }
 
 func NewConsole(config Config, file *os.File) zapcore.WriteSyncer {
+	c := newConsole(file, !config.NoColor && common.ColorsEnabled(config.ForceColors, file))
+	c.noColor = config.NoColor
+	return c
+}
+
+func newConsole(file *os.File, color bool) *Console {
+
+	if !color {
+		return &Console{WriteSyncer: zapcore.AddSync(colorable.NewNonColorable(file)), file: file}
+	}
+
+	return &Console{WriteSyncer: zapcore.AddSync(colorable.NewColorable(file)), colorable: true, file: file}
+
+}
 
-	if config.NoColor {
-		return &Console{zapcore.AddSync(colorable.NewNonColorable(file)), false}
+// KeepColors keeps the colors of an encoder forced to write them, unless
+// NoColor is set.
+func (c *Console) KeepColors() {
+	if c.colorable || c.noColor || c.file == nil {
+		return
 	}
+	c.WriteSyncer = zapcore.AddSync(colorable.NewColorable(c.file))
+	c.colorable = true
+}
 
-	return &Console{zapcore.AddSync(colorable.NewColorable(file)), true}
+// SplitConsole writes the entries from a level on to one file and the
+// others to another, e.g. warnings and errors to stderr and the rest to
+// stdout. The appender encoder and the color choice are shared by both,
+// colors are kept if both files are terminals.
+type SplitConsole struct {
+	low, high *Console
+	level     zapcore.Level
+}
 
+// NewSplit creates a SplitConsole writing the entries from level on to high.
+func NewSplit(config Config, level zapcore.Level, low, high *os.File) *SplitConsole {
+	color := !config.NoColor && common.ColorsEnabled(config.ForceColors, low, high)
+	s := &SplitConsole{
+		low:   newConsole(low, color),
+		high:  newConsole(high, color),
+		level: level,
+	}
+	s.low.noColor, s.high.noColor = config.NoColor, config.NoColor
+	return s
+}
+
+// KeepColors keeps the colors on both files, unless NoColor is set.
+func (s *SplitConsole) KeepColors() {
+	s.low.KeepColors()
+	s.high.KeepColors()
+}
+
+func (s *SplitConsole) WriteEntry(ent zapcore.Entry, _ []zapcore.Field, p []byte) error {
+	w := s.low
+	if ent.Level >= s.level {
+		w = s.high
+	}
+	_, err := w.Write(p)
+	return err
+}
+
+// Write writes p to the file of the lower levels.
+func (s *SplitConsole) Write(p []byte) (int, error) {
+	return s.low.Write(p)
+}
+
+// Sync syncs both files.
+func (s *SplitConsole) Sync() error {
+	err := s.low.Sync()
+	if herr := s.high.Sync(); err == nil {
+		err = herr
+	}
+	return err
 }
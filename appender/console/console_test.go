@@ -0,0 +1,142 @@
+package console
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+target: stdout`, false},
+		{"case2", `
+target: split`, false},
+		{"case3", `
+target: split
+split_level: error`, false},
+		{"case4", `
+target: split
+split_level: loud`, true},
+		{"case5", `
+target: printer`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		_, err = New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+func TestSplitConsole(t *testing.T) {
+	dir := t.TempDir()
+	open := func(name string) *os.File {
+		f, err := os.Create(filepath.Join(dir, name))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		t.Cleanup(func() { _ = f.Close() })
+		return f
+	}
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	s := NewSplit(Config{NoColor: true}, zapcore.WarnLevel, open("out"), open("err"))
+	for _, lvl := range []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel} {
+		assert.NoError(t, s.WriteEntry(zapcore.Entry{Level: lvl}, nil, []byte(lvl.String()+"\n")))
+	}
+	_, err := s.Write([]byte("plain\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Sync())
+
+	assert.Equal(t, "debug\ninfo\nplain\n", read("out"))
+	assert.Equal(t, "warn\nerror\n", read("err"))
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	if len(value) > 0 {
+		_ = os.Setenv(key, value)
+	} else {
+		_ = os.Unsetenv(key)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, old)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestNewConsole_colors(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name    string
+		config  Config
+		noColor string
+		force   string
+		term    string
+		colored bool
+	}{
+		{"not a terminal", Config{}, "", "", "xterm", false},
+		{"force_colors", Config{ForceColors: true}, "1", "", "dumb", true},
+		{"no_color wins", Config{NoColor: true, ForceColors: true}, "", "1", "", false},
+		{"FORCE_COLOR", Config{}, "", "1", "", true},
+		{"FORCE_COLOR=0", Config{}, "", "0", "", false},
+		{"NO_COLOR over FORCE_COLOR", Config{}, "1", "1", "", false},
+		{"TERM=dumb", Config{}, "", "", "dumb", false},
+	}
+
+	for _, c := range tests {
+		setenv(t, "NO_COLOR", c.noColor)
+		setenv(t, "FORCE_COLOR", c.force)
+		setenv(t, "TERM", c.term)
+
+		w := NewConsole(c.config, f).(*Console)
+		assert.Equal(t, c.colored, w.colorable, c.name)
+	}
+}
+
+func TestConsole_KeepColors(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer f.Close()
+	setenv(t, "NO_COLOR", "")
+	setenv(t, "FORCE_COLOR", "")
+
+	w := NewConsole(Config{}, f).(*Console)
+	assert.False(t, w.colorable)
+	w.KeepColors()
+	assert.True(t, w.colorable)
+
+	w = NewConsole(Config{NoColor: true}, f).(*Console)
+	w.KeepColors()
+	assert.False(t, w.colorable)
+
+	s := NewSplit(Config{}, zapcore.WarnLevel, f, f)
+	s.KeepColors()
+	assert.True(t, s.low.colorable)
+	assert.True(t, s.high.colorable)
+}
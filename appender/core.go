@@ -0,0 +1,150 @@
+package appender
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// EntryWriter is implemented by writers that need the original entry next to
+// its encoded form, e.g. to map the level to a protocol severity.
+// The encoded bytes are only valid until WriteEntry returns.
+type EntryWriter interface {
+	WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error
+}
+
+// FallbackWriter is implemented by writers handing the entries they
+// cannot send over to another appender.
+type FallbackWriter interface {
+	FallbackAppender() string
+	SetFallback(core zapcore.Core)
+}
+
+// LinkFallbacks sets the fallback cores of the writers referring to other appenders.
+// Cycles of fallbacks are rejected, an entry failing on all of them would
+// be handed over forever.
+func LinkFallbacks(appenders map[string]*Appender) error {
+	for name := range appenders {
+		if err := checkFallbackCycle(appenders, name); err != nil {
+			return err
+		}
+	}
+	for name, a := range appenders {
+		w, ok := a.Writer.(FallbackWriter)
+		if !ok || len(w.FallbackAppender()) == 0 {
+			continue
+		}
+		fallback, ok := appenders[w.FallbackAppender()]
+		if !ok {
+			return fmt.Errorf("fallback appender %q of %q undefined", w.FallbackAppender(), name)
+		}
+		w.SetFallback(fallback.NewCore(zapcore.DebugLevel))
+	}
+	return nil
+}
+
+// checkFallbackCycle follows the fallbacks from the named appender.
+func checkFallbackCycle(appenders map[string]*Appender, name string) error {
+	chain := []string{name}
+	seen := map[string]bool{name: true}
+	for current := name; ; {
+		a, ok := appenders[current]
+		if !ok {
+			return nil
+		}
+		w, ok := a.Writer.(FallbackWriter)
+		if !ok || len(w.FallbackAppender()) == 0 {
+			return nil
+		}
+		current = w.FallbackAppender()
+		chain = append(chain, current)
+		if current == name {
+			if len(chain) == 2 {
+				return fmt.Errorf("appender %q can not fall back to itself", name)
+			}
+			return fmt.Errorf("appender fallbacks form a cycle: %s", strings.Join(chain, " -> "))
+		}
+		if seen[current] {
+			// a cycle not going through name, reported from its own appenders
+			return nil
+		}
+		seen[current] = true
+	}
+}
+
+// NewCore creates a zapcore.Core writing to the appender.
+// Writers implementing EntryWriter receive the entry and all its fields,
+// including the ones added with With.
+func (a *Appender) NewCore(enab zapcore.LevelEnabler) zapcore.Core {
+	w, ok := a.Writer.(EntryWriter)
+	if !ok {
+		return zapcore.NewCore(a.Encoder, a.Writer, enab)
+	}
+	return &entryCore{
+		LevelEnabler: enab,
+		enc:          a.Encoder.Clone(),
+		out:          a.Writer,
+		w:            w,
+	}
+}
+
+type entryCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	out    zapcore.WriteSyncer
+	w      EntryWriter
+	fields []zapcore.Field
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &entryCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		out:          c.out,
+		w:            c.w,
+		fields:       make([]zapcore.Field, 0, len(c.fields)+len(fields)),
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	all := fields
+	if len(c.fields) > 0 {
+		all = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+		all = append(all, c.fields...)
+		all = append(all, fields...)
+	}
+
+	err = c.w.WriteEntry(ent, all, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Since we may be crashing the program, sync the output.
+		_ = c.Sync()
+	}
+	return nil
+}
+
+func (c *entryCore) Sync() error {
+	return c.out.Sync()
+}
@@ -0,0 +1,81 @@
+package appender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type entryWriter struct {
+	zapcore.WriteSyncer
+	fields   []zapcore.Field
+	fallback string
+	core     zapcore.Core
+}
+
+func (w *entryWriter) WriteEntry(_ zapcore.Entry, fields []zapcore.Field, _ []byte) error {
+	w.fields = fields
+	return nil
+}
+
+func (w *entryWriter) Sync() error { return nil }
+
+func (w *entryWriter) FallbackAppender() string { return w.fallback }
+
+func (w *entryWriter) SetFallback(core zapcore.Core) { w.core = core }
+
+func newTestAppender(w zapcore.WriteSyncer) *Appender {
+	return &Appender{
+		Writer:  w,
+		Encoder: zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+	}
+}
+
+func TestAppender_NewCore(t *testing.T) {
+	w := &entryWriter{}
+	core := newTestAppender(w).NewCore(zapcore.InfoLevel)
+
+	logger := zap.New(core).With(zap.String("with", "1"))
+	logger.Debug("skipped", zap.String("call", "0"))
+	assert.Nil(t, w.fields)
+
+	logger.Info("written", zap.String("call", "2"))
+	assert.Equal(t, []zapcore.Field{zap.String("with", "1"), zap.String("call", "2")}, w.fields)
+}
+
+func TestLinkFallbacks(t *testing.T) {
+	w := &entryWriter{fallback: "FALLBACK"}
+	appenders := map[string]*Appender{
+		"MAIN":     newTestAppender(w),
+		"FALLBACK": newTestAppender(&entryWriter{}),
+	}
+	assert.NoError(t, LinkFallbacks(appenders))
+	assert.NotNil(t, w.core)
+
+	w.fallback = "UNKNOWN"
+	assert.Error(t, LinkFallbacks(appenders))
+
+	w.fallback = "MAIN"
+	assert.Error(t, LinkFallbacks(appenders))
+}
+
+func TestLinkFallbacks_cycle(t *testing.T) {
+	a := &entryWriter{fallback: "B"}
+	b := &entryWriter{fallback: "C"}
+	c := &entryWriter{}
+	appenders := map[string]*Appender{
+		"A": newTestAppender(a),
+		"B": newTestAppender(b),
+		"C": newTestAppender(c),
+	}
+	assert.NoError(t, LinkFallbacks(appenders))
+
+	b.fallback = "A"
+	assert.Error(t, LinkFallbacks(appenders))
+
+	b.fallback = "C"
+	c.fallback = "B"
+	assert.Error(t, LinkFallbacks(appenders))
+}
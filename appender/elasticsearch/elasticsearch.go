@@ -0,0 +1,272 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/httpclient"
+	"go.uber.org/zap/zapcore"
+)
+
+const bulkPath = "/_bulk"
+
+type Config struct {
+	// Config.URL is the cluster address, the _bulk path is appended if missing.
+	// MaxRetries also limits the retries of items rejected by the cluster.
+	httpclient.Config `logos-config:",inline"`
+
+	// Index is the target index. It supports the date verbs of the
+	// rolling_file names, %Y, %m, %d, %H, %M and %j, replaced with the UTC
+	// time of the entry.
+	Index string `logos-config:"index" logos-validate:"required"`
+	// DataStream sends the entries with the create action, as data streams
+	// require. The documents must contain the @timestamp field.
+	DataStream bool `logos-config:"data_stream"`
+
+	Username string `logos-config:"username"`
+	Password string `logos-config:"password"`
+	APIKey   string `logos-config:"api_key"`
+
+	Batch batch.Config `logos-config:"batch"`
+}
+
+var (
+	defaultConfig = Config{
+		Config: httpclient.DefaultConfig,
+		Index:  "logs-%Y.%m.%d",
+		Batch: batch.Config{
+			Size:     500,
+			Bytes:    5 << 20,
+			Interval: time.Second,
+		},
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+// BulkError is returned when the cluster did not accept all items of a bulk request.
+type BulkError struct {
+	Failed int
+	// Reason is the error of the first failed item.
+	Reason string
+}
+
+// merge adds the failures of other, keeping the first reason.
+func (e *BulkError) merge(other *BulkError) *BulkError {
+	if e == nil {
+		return other
+	}
+	e.Failed += other.Failed
+	return e
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("elasticsearch bulk: %d items failed: %s", e.Failed, e.Reason)
+}
+
+// Writer indexes entries through the Elasticsearch (or OpenSearch) bulk API.
+type Writer struct {
+	client  *httpclient.Client
+	header  http.Header
+	index   string
+	action  string
+	retries int
+	backoff common.Backoff
+	batcher *batch.Batcher
+}
+
+type document struct {
+	action []byte
+	source []byte
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if len(cfg.Index) == 0 {
+		return nil, fmt.Errorf("elasticsearch index is required")
+	}
+	if len(cfg.URL) > 0 && !strings.HasSuffix(cfg.URL, bulkPath) {
+		cfg.URL = strings.TrimSuffix(cfg.URL, "/") + bulkPath
+	}
+	client, err := httpclient.New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case len(cfg.APIKey) > 0:
+		header.Set("Authorization", "ApiKey "+cfg.APIKey)
+	case len(cfg.Username) > 0:
+		auth := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+
+	w := &Writer{
+		client:  client,
+		header:  header,
+		index:   cfg.Index,
+		action:  "index",
+		retries: cfg.MaxRetries,
+		backoff: common.Backoff{
+			Min: cfg.BackoffMin,
+			Max: cfg.BackoffMax,
+		},
+	}
+	if cfg.DataStream {
+		w.action = "create"
+	}
+	w.batcher = batch.New(cfg.Batch, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.add(time.Now(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, _ []zapcore.Field, p []byte) error {
+	return w.add(ent.Time, p)
+}
+
+// Sync sends the queued entries.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	return w.batcher.Close()
+}
+
+func (w *Writer) add(t time.Time, p []byte) error {
+	doc := &document{
+		action: []byte(fmt.Sprintf(`{%q:{"_index":%q}}`, w.action, FormatIndex(w.index, t))),
+		source: append([]byte(nil), bytes.TrimRight(p, "\r\n")...),
+	}
+	return w.batcher.Add(doc, len(doc.action)+len(doc.source)+2)
+}
+
+type bulkResponse struct {
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+type bulkItemResult struct {
+	Status int `json:"status"`
+	Error  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// retryable reports whether the item was rejected because the cluster was busy.
+func (r bulkItemResult) retryable() bool {
+	return r.Status == http.StatusTooManyRequests || r.Status >= 500
+}
+
+// send posts the documents and sends the rejected ones again, up to the
+// configured number of retries. It runs on the batch worker only.
+func (w *Writer) send(items []interface{}) error {
+	docs := make([]*document, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item.(*document))
+	}
+
+	var failed *BulkError
+	w.backoff.Reset()
+	for {
+		retry, err := w.bulk(docs)
+		if bulkErr, ok := err.(*BulkError); ok {
+			failed = failed.merge(bulkErr)
+		} else if err != nil {
+			return err
+		}
+
+		if len(retry) > 0 && w.backoff.Attempts() >= w.retries {
+			failed = failed.merge(&BulkError{Failed: len(retry), Reason: "rejected, retries exhausted"})
+			retry = nil
+		}
+		if len(retry) == 0 {
+			if failed != nil {
+				return failed
+			}
+			return nil
+		}
+		time.Sleep(w.backoff.Next())
+		docs = retry
+	}
+}
+
+// bulk sends one request. It returns the documents to retry and the error
+// of the documents failed permanently.
+func (w *Writer) bulk(docs []*document) ([]*document, error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		body.Write(doc.action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+
+	resp, err := w.client.Post(body.Bytes(), w.header)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bulkResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("elasticsearch bulk: invalid response: %v", err)
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+	if len(result.Items) != len(docs) {
+		return nil, fmt.Errorf("elasticsearch bulk: got %d results for %d items", len(result.Items), len(docs))
+	}
+
+	var (
+		retry   []*document
+		bulkErr *BulkError
+	)
+	for i, item := range result.Items {
+		for _, r := range item {
+			switch {
+			case r.Status < 300:
+			case r.retryable():
+				retry = append(retry, docs[i])
+			case bulkErr == nil:
+				bulkErr = &BulkError{Failed: 1, Reason: r.Error.Type + ": " + r.Error.Reason}
+			default:
+				bulkErr.Failed++
+			}
+		}
+	}
+	if bulkErr != nil {
+		return retry, bulkErr
+	}
+	return retry, nil
+}
+
+// FormatIndex replaces the date verbs of the index pattern with the UTC time t.
+func FormatIndex(pattern string, t time.Time) string {
+	return common.ExpandDate(pattern, t.UTC())
+}
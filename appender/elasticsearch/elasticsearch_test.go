@@ -0,0 +1,227 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewElasticsearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+url: http://127.0.0.1:9200
+encoder:
+ json:`, false},
+		{"case2", `
+index: logs
+encoder:
+ json:`, true},
+		{"case3", `
+url: http://127.0.0.1:9200
+index: logs-app
+data_stream: true
+encoder:
+ json:`, false},
+		{"case4", `
+url: http://127.0.0.1:9200
+index: ""
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+func TestFormatIndex(t *testing.T) {
+	ts := time.Date(2020, 9, 3, 7, 0, 0, 0, time.FixedZone("MSK", 3*3600))
+	assert.Equal(t, "logs-2020.09.03", FormatIndex("logs-%Y.%m.%d", ts))
+	assert.Equal(t, "logs-2020.09.03.04", FormatIndex("logs-%Y.%m.%d.%H", ts))
+	assert.Equal(t, "logs-2020.247.04-00", FormatIndex("logs-%Y.%j.%H-%M", ts))
+	assert.Equal(t, "logs-%q-%", FormatIndex("logs-%q-%%", ts))
+	assert.Equal(t, "logs", FormatIndex("logs", ts))
+}
+
+// cluster is a stand-in for the _bulk endpoint. Each response rejects the
+// documents listed in reject with the matching status.
+type cluster struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	reject   []map[string]int
+}
+
+func (c *cluster) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, _ := ioutil.ReadAll(req.Body)
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, string(data))
+
+	var reject map[string]int
+	if len(c.reject) > 0 {
+		reject, c.reject = c.reject[0], c.reject[1:]
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	type item struct {
+		Status int               `json:"status"`
+		Error  map[string]string `json:"error,omitempty"`
+	}
+	resp := struct {
+		Errors bool              `json:"errors"`
+		Items  []map[string]item `json:"items"`
+	}{}
+	for i := 0; i < len(lines); i += 2 {
+		var action map[string]interface{}
+		_ = json.Unmarshal([]byte(lines[i]), &action)
+		for op := range action {
+			it := item{Status: http.StatusCreated}
+			if status, ok := reject[lines[i+1]]; ok {
+				resp.Errors = true
+				it = item{Status: status, Error: map[string]string{"type": "test_exception", "reason": "rejected"}}
+			}
+			resp.Items = append(resp.Items, map[string]item{op: it})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (c *cluster) get() ([]*http.Request, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests, c.bodies
+}
+
+func testConfig(url string) Config {
+	config := DefaultConfig()
+	config.URL = url
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	return config
+}
+
+var entryTime = time.Date(2020, 9, 3, 7, 0, 0, 0, time.UTC)
+
+func TestWriter_bulk(t *testing.T) {
+	c := &cluster{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL + "/")
+	config.Username = "elastic"
+	config.Password = "secret"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ent := zapcore.Entry{Time: entryTime}
+	assert.NoError(t, w.WriteEntry(ent, nil, []byte(`{"msg":"1"}`+"\n")))
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{Time: entryTime.AddDate(0, 0, 1)}, nil, []byte(`{"msg":"2"}`+"\n")))
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := c.get()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/_bulk", requests[0].URL.Path)
+	assert.Equal(t, "application/x-ndjson", requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "Basic ZWxhc3RpYzpzZWNyZXQ=", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, `{"index":{"_index":"logs-2020.09.03"}}
+{"msg":"1"}
+{"index":{"_index":"logs-2020.09.04"}}
+{"msg":"2"}
+`, bodies[0])
+}
+
+func TestWriter_dataStream(t *testing.T) {
+	c := &cluster{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Index = "logs-app-default"
+	config.DataStream = true
+	config.APIKey = "key"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"@timestamp":"2020-09-03T07:00:00Z"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := c.get()
+	assert.Equal(t, "ApiKey key", requests[0].Header.Get("Authorization"))
+	assert.True(t, strings.HasPrefix(bodies[0], `{"create":{"_index":"logs-app-default"}}`+"\n"), bodies[0])
+}
+
+func TestWriter_retryRejected(t *testing.T) {
+	c := &cluster{reject: []map[string]int{
+		{`{"msg":"2"}`: http.StatusTooManyRequests, `{"msg":"3"}`: http.StatusBadRequest},
+		{`{"msg":"2"}`: http.StatusTooManyRequests},
+	}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	w, err := NewWriter(testConfig(srv.URL))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	for _, msg := range []string{`{"msg":"1"}`, `{"msg":"2"}`, `{"msg":"3"}`} {
+		_, err = w.Write([]byte(msg + "\n"))
+		assert.NoError(t, err)
+	}
+	err = w.Sync()
+
+	bulkErr, ok := err.(*BulkError)
+	assert.True(t, ok, err)
+	assert.Equal(t, 1, bulkErr.Failed)
+	assert.Equal(t, "test_exception: rejected", bulkErr.Reason)
+
+	_, bodies := c.get()
+	assert.Len(t, bodies, 3)
+	assert.Equal(t, `{"index":{"_index":"logs-`+time.Now().UTC().Format("2006.01.02")+`"}}`+"\n"+`{"msg":"2"}`+"\n", bodies[2])
+}
+
+func TestWriter_retriesExhausted(t *testing.T) {
+	c := &cluster{reject: []map[string]int{
+		{`{"msg":"1"}`: http.StatusTooManyRequests},
+		{`{"msg":"1"}`: http.StatusTooManyRequests},
+	}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.MaxRetries = 1
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"1"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Error(t, w.Sync())
+
+	_, bodies := c.get()
+	assert.Len(t, bodies, 2)
+}
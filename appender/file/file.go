@@ -1,21 +1,85 @@
 package file
 
 import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+
 	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/flock"
 	"go.uber.org/zap/zapcore"
-	"os"
 )
 
+// File appends to a file. It reopens the path when the file was moved or
+// removed, e.g. by logrotate, once a check finds it or on ReopenSignal.
 type File struct {
-	*os.File
+	name          string
+	fileMode      os.FileMode
+	createDirs    bool
+	dirMode       os.FileMode
+	checkInterval time.Duration
+	checkOnSync   bool
+	copyTruncate  bool
+	flock         bool
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	info os.FileInfo
+	// size is the length of the file known from the writes
+	size      int64
+	lastCheck time.Time
+	closed    bool
+
+	signals chan os.Signal
+	done    chan struct{}
 }
 
 type Config struct {
+	// FileName is the path of the file, config variables like ${pid} or
+	// ${hostname} give every process its own file.
 	FileName string `logos-config:"file_name" logos-validate:"required"`
+
+	// FileMode is the permission of a created file, 0644 by default.
+	FileMode common.FileMode `logos-config:"file_mode"`
+
+	// CreateDirs creates the missing directories of the file with DirMode,
+	// 0755 by default.
+	CreateDirs bool            `logos-config:"create_dirs"`
+	DirMode    common.FileMode `logos-config:"dir_mode"`
+
+	// CheckInterval is the minimal time between two checks made on write
+	// that the path still names the open file. Checks on write are
+	// disabled if 0.
+	CheckInterval time.Duration `logos-config:"check_interval" logos-validate:"min=0"`
+
+	// CheckOnSync checks that the path still names the open file on Sync.
+	CheckOnSync bool `logos-config:"check_on_sync"`
+
+	// CopyTruncate is for files rotated in place, like logrotate
+	// copytruncate: the path is not checked for a new file, the checks
+	// reopen the file when it is shorter than written, i.e. truncated.
+	// Since the file is opened for appending, writes continue at the start
+	// after the truncation in any mode.
+	CopyTruncate bool `logos-config:"copytruncate"`
+
+	// ReopenSignal is the signal name (e.g. SIGUSR1) that reopens the file.
+	ReopenSignal string `logos-config:"reopen_signal"`
+
+	// Flock holds an advisory lock of the file during every write, so
+	// entries of processes sharing the file never interleave.
+	Flock bool `logos-config:"flock"`
 }
 
 var (
-	defaultConfig = Config{}
+	defaultConfig = Config{
+		FileMode: 0644,
+		DirMode:  0755,
+	}
 )
 
 func DefaultConfig() Config {
@@ -27,9 +91,184 @@ func New(v *common.Config) (zapcore.WriteSyncer, error) {
 	if err := v.Unpack(&cfg); err != nil {
 		return nil, err
 	}
-	f, err := os.OpenFile(cfg.FileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
-	if err != nil {
+	return NewFile(cfg)
+}
+
+// NewFile opens the file of the config.
+func NewFile(cfg Config) (*File, error) {
+	var sig os.Signal
+	if len(cfg.ReopenSignal) > 0 {
+		var err error
+		if sig, err = common.ParseSignal(cfg.ReopenSignal); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &File{
+		name:          cfg.FileName,
+		fileMode:      cfg.FileMode.Perm(),
+		createDirs:    cfg.CreateDirs,
+		dirMode:       cfg.DirMode.Perm(),
+		checkInterval: cfg.CheckInterval,
+		checkOnSync:   cfg.CheckOnSync,
+		copyTruncate:  cfg.CopyTruncate,
+		flock:         cfg.Flock,
+		now:           time.Now,
+	}
+	if f.fileMode == 0 {
+		f.fileMode = defaultConfig.FileMode.Perm()
+	}
+	if f.dirMode == 0 {
+		f.dirMode = defaultConfig.DirMode.Perm()
+	}
+	if err := f.open(); err != nil {
 		return nil, err
 	}
-	return &File{f}, nil
+
+	if sig != nil {
+		f.signals = make(chan os.Signal, 1)
+		f.done = make(chan struct{})
+		signal.Notify(f.signals, sig)
+		go f.handleSignals(f.signals, f.done)
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	if f.createDirs {
+		if err := os.MkdirAll(filepath.Dir(f.name), f.dirMode); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(f.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, f.fileMode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file = file
+	f.info = info
+	f.size = info.Size()
+	f.lastCheck = f.now()
+	return nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.checkInterval > 0 && f.now().Sub(f.lastCheck) >= f.checkInterval {
+		if err := f.check(); err != nil {
+			return 0, err
+		}
+	}
+	if f.flock {
+		if err := flock.Lock(f.file); err != nil {
+			return 0, err
+		}
+		defer flock.Unlock(f.file)
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	err := f.file.Sync()
+	if f.checkOnSync {
+		if cerr := f.check(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// check reopens the path if it no longer names the open file.
+func (f *File) check() error {
+	f.lastCheck = f.now()
+	if f.copyTruncate {
+		return f.checkTruncated()
+	}
+	info, err := os.Stat(f.name)
+	if err == nil && os.SameFile(info, f.info) {
+		return nil
+	}
+	return f.open()
+}
+
+// checkTruncated reopens the file if it is shorter than written, it was
+// truncated in place.
+func (f *File) checkTruncated() error {
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= f.size {
+		// other processes may append to the file too
+		f.size = info.Size()
+		return nil
+	}
+	return f.open()
+}
+
+// Reopen closes the file and opens the path again.
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.open()
+}
+
+// Name returns the path of the file.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Close stops listening for the reopen signal and closes the file. The
+// later writes fail with os.ErrClosed.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if f.signals != nil {
+		signal.Stop(f.signals)
+		close(f.done)
+		f.signals = nil
+	}
+	return f.file.Close()
+}
+
+func (f *File) handleSignals(signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case <-signals:
+			_ = f.Reopen()
+		case <-done:
+			return
+		}
+	}
 }
@@ -1,8 +1,18 @@
 package file
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/khorevaa/logos/internal/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -10,3 +20,234 @@ func TestDefaultConfig(t *testing.T) {
 	c := DefaultConfig()
 	assert.Empty(t, c.FileName)
 }
+
+func TestNewFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+file_name: ` + filepath.Join(dir, "app.log") + `
+encoder:
+ json:`, false},
+		{"case2", `
+encoder:
+ json:`, true},
+		{"case3", `
+file_name: ` + filepath.Join(dir, "app.log") + `
+check_interval: 10s
+check_on_sync: true
+copytruncate: true
+encoder:
+ json:`, false},
+		{"case4", `
+file_name: ` + filepath.Join(dir, "app.log") + `
+reopen_signal: SIGNOPE
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			assert.NoError(t, w.(*File).Close())
+		}
+	}
+}
+
+func read(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func write(t *testing.T, f *File, s string) {
+	_, err := f.Write([]byte(s))
+	assert.NoError(t, err)
+}
+
+func TestFile_checkOnSync(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewFile(Config{FileName: name, CheckOnSync: true})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	write(t, f, "before\n")
+	assert.NoError(t, os.Rename(name, name+".1"))
+	write(t, f, "moved\n")
+	assert.NoError(t, f.Sync())
+	write(t, f, "after\n")
+
+	assert.Equal(t, "before\nmoved\n", read(t, name+".1"))
+	assert.Equal(t, "after\n", read(t, name))
+
+	assert.NoError(t, os.Remove(name))
+	assert.NoError(t, f.Sync())
+	write(t, f, "recreated\n")
+	assert.Equal(t, "recreated\n", read(t, name))
+}
+
+func TestFile_checkInterval(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewFile(Config{FileName: name, CheckInterval: time.Minute})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	now := time.Now()
+	f.now = func() time.Time { return now }
+	assert.NoError(t, os.Rename(name, name+".1"))
+	write(t, f, "moved\n")
+
+	now = now.Add(time.Minute)
+	write(t, f, "after\n")
+
+	assert.Equal(t, "moved\n", read(t, name+".1"))
+	assert.Equal(t, "after\n", read(t, name))
+}
+
+func TestFile_Close(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewFile(Config{FileName: name, CheckInterval: time.Nanosecond})
+	assert.NoError(t, err)
+
+	write(t, f, "before\n")
+	assert.NoError(t, f.Close())
+	assert.NoError(t, os.Remove(name))
+
+	_, err = f.Write([]byte("late\n"))
+	assert.Equal(t, os.ErrClosed, err)
+	assert.NoError(t, f.Sync())
+	assert.NoError(t, f.Close())
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFile_copyTruncate(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewFile(Config{FileName: name, CheckOnSync: true, CopyTruncate: true})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	write(t, f, "before truncate\n")
+	opened := f.file
+	assert.NoError(t, f.Sync())
+	assert.Same(t, opened, f.file)
+
+	assert.NoError(t, os.Truncate(name, 0))
+	assert.NoError(t, f.Sync())
+	assert.NotSame(t, opened, f.file)
+	assert.Equal(t, int64(0), f.size)
+	write(t, f, "after\n")
+	assert.Equal(t, int64(len("after\n")), f.size)
+
+	assert.Equal(t, "after\n", read(t, name))
+
+	// the path is not checked for a new file
+	assert.NoError(t, os.Rename(name, name+".1"))
+	assert.NoError(t, f.Sync())
+	write(t, f, "moved\n")
+	assert.Equal(t, "after\nmoved\n", read(t, name+".1"))
+}
+
+func TestFile_reopenSignal(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewFile(Config{FileName: name})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go f.handleSignals(signals, done)
+
+	assert.NoError(t, os.Rename(name, name+".1"))
+	signals <- syscall.SIGHUP
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(name); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	write(t, f, "reopened\n")
+	assert.Equal(t, "reopened\n", read(t, name))
+}
+
+func TestFile_modesAndVars(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := common.NewConfigFrom(`
+file_name: ` + filepath.Join(dir, "logs", "${app}-${pid}.log") + `
+file_mode: 0600
+dir_mode: "0700"
+create_dirs: true
+`)
+	assert.NoError(t, err)
+	w, err := New(cfg)
+	assert.NoError(t, err)
+	f := w.(*File)
+	defer f.Close()
+
+	vars := common.ProcessVars()
+	assert.Equal(t, filepath.Join(dir, "logs", vars["app"]+"-"+strconv.Itoa(os.Getpid())+".log"), f.Name())
+
+	info, err := os.Stat(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dir, "logs"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	cfg, err = common.NewConfigFrom(`
+file_name: ` + filepath.Join(dir, "missing", "app.log") + `
+`)
+	assert.NoError(t, err)
+	_, err = New(cfg)
+	assert.Error(t, err)
+
+	cfg, err = common.NewConfigFrom(`
+file_name: ` + filepath.Join(dir, "app.log") + `
+file_mode: "0999"
+`)
+	assert.NoError(t, err)
+	_, err = New(cfg)
+	assert.Error(t, err)
+}
+
+func TestFile_flock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+
+	var files []*File
+	for i := 0; i < 2; i++ {
+		f, err := NewFile(Config{FileName: name, Flock: true})
+		assert.NoError(t, err)
+		defer f.Close()
+		files = append(files, f)
+	}
+
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		go func(i int, f *File) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				write(t, f, fmt.Sprintf("file %d line %03d\n", i, j))
+			}
+		}(i, f)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(read(t, name), "\n"), "\n")
+	assert.Len(t, lines, 200)
+	for _, line := range lines {
+		assert.Regexp(t, `^file \d line \d{3}$`, line)
+	}
+}
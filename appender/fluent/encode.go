@@ -0,0 +1,70 @@
+package fluent
+
+import (
+	"github.com/khorevaa/logos/internal/msgpack"
+)
+
+// encode groups the events into protocol messages of the configured mode.
+// The messages lack their last element, the option map, which is added
+// when they are sent.
+func (w *Writer) encode(items []interface{}) [][]byte {
+	if w.mode == ModeMessage {
+		msgs := make([][]byte, 0, len(items))
+		for _, item := range items {
+			e := item.(*event)
+			var b msgpack.Buffer
+			b.ArrayHeader(4)
+			b.String(e.tag)
+			w.encodeEntry(&b, e)
+			msgs = append(msgs, b.Bytes())
+		}
+		return msgs
+	}
+
+	var (
+		tags   []string
+		events = map[string][]*event{}
+	)
+	for _, item := range items {
+		e := item.(*event)
+		if _, ok := events[e.tag]; !ok {
+			tags = append(tags, e.tag)
+		}
+		events[e.tag] = append(events[e.tag], e)
+	}
+
+	msgs := make([][]byte, 0, len(tags))
+	for _, tag := range tags {
+		var b msgpack.Buffer
+		b.ArrayHeader(3)
+		b.String(tag)
+
+		switch w.mode {
+		case ModePackedForward:
+			var entries msgpack.Buffer
+			for _, e := range events[tag] {
+				entries.ArrayHeader(2)
+				w.encodeEntry(&entries, e)
+			}
+			b.Binary(entries.Bytes())
+		default:
+			b.ArrayHeader(len(events[tag]))
+			for _, e := range events[tag] {
+				b.ArrayHeader(2)
+				w.encodeEntry(&b, e)
+			}
+		}
+		msgs = append(msgs, b.Bytes())
+	}
+	return msgs
+}
+
+// encodeEntry appends the time and the record of the event.
+func (w *Writer) encodeEntry(b *msgpack.Buffer, e *event) {
+	if w.eventTime {
+		b.EventTime(e.time)
+	} else {
+		b.Int(e.time.Unix())
+	}
+	b.Raw(e.record)
+}
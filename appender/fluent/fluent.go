@@ -0,0 +1,363 @@
+package fluent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/msgpack"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	Network string           `logos-config:"network" logos-validate:"logos.oneof=tcp unix"`
+	Address string           `logos-config:"address" logos-validate:"required"`
+	TLS     common.TLSConfig `logos-config:"tls"`
+
+	// Tag of the events. The {logger} and {level} placeholders are
+	// replaced with the logger name and the level.
+	Tag string `logos-config:"tag" logos-validate:"required"`
+	// Mode is the forward protocol carrier mode.
+	Mode string `logos-config:"mode" logos-validate:"logos.oneof=message forward packed_forward"`
+	// EventTime sends the time with nanoseconds, integer seconds otherwise.
+	EventTime bool `logos-config:"event_time"`
+	// MessageKey is the record key of entries not encoded as JSON objects.
+	MessageKey string `logos-config:"message_key"`
+
+	// RequireAck waits for the server to acknowledge every chunk.
+	RequireAck bool          `logos-config:"require_ack"`
+	AckTimeout time.Duration `logos-config:"ack_timeout"`
+
+	// SharedKey enables the handshake, Username and Password are sent
+	// when the server requires user authentication.
+	SharedKey    string `logos-config:"shared_key"`
+	SelfHostname string `logos-config:"self_hostname"`
+	Username     string `logos-config:"username"`
+	Password     string `logos-config:"password"`
+
+	// Timeout applies to dialing and to each write.
+	Timeout    time.Duration `logos-config:"timeout"`
+	BackoffMin time.Duration `logos-config:"backoff_min"`
+	BackoffMax time.Duration `logos-config:"backoff_max"`
+	// BufferSize is the number of protocol messages kept while disconnected.
+	BufferSize int `logos-config:"buffer_size" logos-validate:"min=0"`
+
+	Batch batch.Config `logos-config:"batch"`
+}
+
+var (
+	defaultConfig = Config{
+		Network:    "tcp",
+		Tag:        "{logger}",
+		Mode:       ModeForward,
+		EventTime:  true,
+		MessageKey: "message",
+		AckTimeout: 30 * time.Second,
+		Timeout:    5 * time.Second,
+		BackoffMin: 100 * time.Millisecond,
+		BackoffMax: 30 * time.Second,
+		BufferSize: 1000,
+		Batch: batch.Config{
+			Size:     100,
+			Bytes:    1 << 20,
+			Interval: time.Second,
+		},
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+const (
+	ModeMessage       = "message"
+	ModeForward       = "forward"
+	ModePackedForward = "packed_forward"
+)
+
+// rootTag replaces the empty logger name in tags.
+const rootTag = "root"
+
+var ErrNotConnected = errors.New("fluent forward is not connected")
+
+// Writer sends entries with the Fluentd Forward protocol, reconnecting with
+// backoff and buffering messages while disconnected.
+type Writer struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+
+	tag        string
+	mode       string
+	eventTime  bool
+	messageKey string
+
+	requireAck bool
+	ackTimeout time.Duration
+
+	sharedKey    string
+	selfHostname string
+	username     string
+	password     string
+
+	mu        sync.Mutex
+	conn      net.Conn
+	reconnect common.Reconnect
+	buffer    *common.MessageQueue
+
+	batcher *batch.Batcher
+}
+
+type event struct {
+	tag    string
+	time   time.Time
+	record []byte
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if len(cfg.Address) == 0 {
+		return nil, errors.New("fluent forward address is required")
+	}
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		network:      cfg.Network,
+		address:      cfg.Address,
+		tlsConfig:    tlsConfig,
+		timeout:      cfg.Timeout,
+		tag:          cfg.Tag,
+		mode:         cfg.Mode,
+		eventTime:    cfg.EventTime,
+		messageKey:   cfg.MessageKey,
+		requireAck:   cfg.RequireAck,
+		ackTimeout:   cfg.AckTimeout,
+		sharedKey:    cfg.SharedKey,
+		selfHostname: cfg.SelfHostname,
+		username:     cfg.Username,
+		password:     cfg.Password,
+		reconnect: common.Reconnect{
+			Name: fmt.Sprintf("fluent forward %s://%s", cfg.Network, cfg.Address),
+			Backoff: common.Backoff{
+				Min: cfg.BackoffMin,
+				Max: cfg.BackoffMax,
+			},
+		},
+		buffer: common.NewMessageQueue(cfg.BufferSize),
+	}
+	if len(w.sharedKey) > 0 && len(w.selfHostname) == 0 {
+		if w.selfHostname, err = os.Hostname(); err != nil {
+			return nil, err
+		}
+	}
+
+	w.batcher = batch.New(cfg.Batch, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.add(w.formatTag("", zapcore.InfoLevel), time.Now(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, _ []zapcore.Field, p []byte) error {
+	return w.add(w.formatTag(ent.LoggerName, ent.Level), ent.Time, p)
+}
+
+// Sync sends the queued entries. It fails while disconnected.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	err := w.batcher.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+// Buffered returns the number of messages waiting for the connection.
+func (w *Writer) Buffered() int {
+	return w.buffer.Len()
+}
+
+// Dropped returns the number of messages dropped because the buffer was full.
+func (w *Writer) Dropped() int {
+	return w.buffer.Dropped()
+}
+
+func (w *Writer) formatTag(logger string, level zapcore.Level) string {
+	if strings.IndexByte(w.tag, '{') < 0 {
+		return w.tag
+	}
+	if len(logger) == 0 {
+		logger = rootTag
+	}
+	return strings.NewReplacer("{logger}", logger, "{level}", level.String()).Replace(w.tag)
+}
+
+func (w *Writer) add(tag string, t time.Time, p []byte) error {
+	var b msgpack.Buffer
+	p = bytes.TrimRight(p, "\r\n")
+
+	var record map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&record); err == nil && record != nil && !dec.More() {
+		b.Value(record)
+	} else {
+		b.MapHeader(1)
+		b.String(w.messageKey)
+		b.String(string(p))
+	}
+
+	return w.batcher.Add(&event{tag: tag, time: t, record: b.Bytes()}, b.Len())
+}
+
+// send encodes the events and writes them after the buffered messages.
+func (w *Writer) send(items []interface{}) error {
+	for _, msg := range w.encode(items) {
+		w.buffer.Push(msg)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// flush sends the buffered messages in order, w.mu must be held.
+func (w *Writer) flush() error {
+	for {
+		msg, ok := w.buffer.Pop()
+		if !ok {
+			return nil
+		}
+		if err := w.sendMessage(msg); err != nil {
+			w.buffer.PushFront(msg)
+			return err
+		}
+	}
+}
+
+// sendMessage writes one protocol message, connecting first if needed,
+// and waits for its ack. w.mu must be held.
+func (w *Writer) sendMessage(msg []byte) error {
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	// the option map is the last element of every message
+	b := msgpack.Buffer{}
+	b.Raw(msg)
+	var chunk string
+	if w.requireAck {
+		var err error
+		if chunk, err = newChunkID(); err != nil {
+			return err
+		}
+		b.MapHeader(1)
+		b.String("chunk")
+		b.String(chunk)
+	} else {
+		b.MapHeader(0)
+	}
+
+	if w.timeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+	_, err := w.conn.Write(b.Bytes())
+	if err == nil && w.requireAck {
+		err = w.readAck(chunk)
+	}
+	if err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.reconnect.Failed(err)
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) readAck(chunk string) error {
+	_ = w.conn.SetReadDeadline(time.Now().Add(w.ackTimeout))
+	defer func() { _ = w.conn.SetReadDeadline(time.Time{}) }()
+
+	v, err := msgpack.NewDecoder(w.conn).Decode()
+	if err != nil {
+		return fmt.Errorf("fluent forward ack: %v", err)
+	}
+	resp, _ := v.(map[string]interface{})
+	if ack, _ := resp["ack"].(string); ack != chunk {
+		return fmt.Errorf("fluent forward ack: unexpected response %v", v)
+	}
+	return nil
+}
+
+func (w *Writer) connect() error {
+	if w.reconnect.Waiting() {
+		return ErrNotConnected
+	}
+
+	dialer := &net.Dialer{Timeout: w.timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, w.network, w.address, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err == nil && len(w.sharedKey) > 0 {
+		if err = w.handshake(conn); err != nil {
+			_ = conn.Close()
+		}
+	}
+	if err != nil {
+		w.reconnect.Failed(err)
+		return err
+	}
+
+	w.conn = conn
+	w.reconnect.Connected()
+	return nil
+}
+
+func newChunkID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b[:]), nil
+}
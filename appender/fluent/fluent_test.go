@@ -0,0 +1,335 @@
+package fluent
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/msgpack"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewFluentForward(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+address: 127.0.0.1:24224
+encoder:
+ json:`, false},
+		{"case2", `
+tag: app
+encoder:
+ json:`, true},
+		{"case3", `
+address: 127.0.0.1:24224
+mode: compressed
+encoder:
+ json:`, true},
+		{"case4", `
+network: unix
+address: /var/run/fluent.sock
+mode: packed_forward
+require_ack: true
+shared_key: secret
+encoder:
+ json:`, false},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+// server is a forward input stand-in, it decodes the received messages
+// and acks them when asked to.
+type server struct {
+	ln        net.Listener
+	sharedKey string
+	messages  chan []interface{}
+}
+
+func newServer(t *testing.T, network, address string) *server {
+	ln, err := net.Listen(network, address)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	s := &server{ln: ln, messages: make(chan []interface{}, 100)}
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *server) serve(t *testing.T) {
+	go func() {
+		for {
+			conn, err := s.ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(t, conn)
+		}
+	}()
+}
+
+func (s *server) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	dec := msgpack.NewDecoder(conn)
+
+	if len(s.sharedKey) > 0 && !s.handshake(t, conn, dec) {
+		return
+	}
+
+	for {
+		v, err := dec.Decode()
+		if err != nil {
+			return
+		}
+		msg := v.([]interface{})
+		s.messages <- msg
+
+		option, _ := msg[len(msg)-1].(map[string]interface{})
+		if chunk, ok := option["chunk"]; ok {
+			var b msgpack.Buffer
+			b.MapHeader(1)
+			b.String("ack")
+			b.Value(chunk)
+			_, _ = conn.Write(b.Bytes())
+		}
+	}
+}
+
+func (s *server) handshake(t *testing.T, conn net.Conn, dec *msgpack.Decoder) bool {
+	nonce := []byte("nonce")
+
+	var b msgpack.Buffer
+	b.ArrayHeader(2)
+	b.String("HELO")
+	b.MapHeader(3)
+	b.String("nonce")
+	b.Binary(nonce)
+	b.String("auth")
+	b.Binary(nil)
+	b.String("keepalive")
+	b.Bool(true)
+	_, _ = conn.Write(b.Bytes())
+
+	v, err := dec.Decode()
+	if !assert.NoError(t, err) {
+		return false
+	}
+	ping := v.([]interface{})
+	assert.Equal(t, "PING", ping[0])
+	hostname, salt := ping[1].(string), ping[2].([]byte)
+	ok := ping[3] == digest(salt, []byte(hostname), nonce, []byte(s.sharedKey))
+
+	b.Reset()
+	b.ArrayHeader(5)
+	b.String("PONG")
+	b.Bool(ok)
+	b.String("")
+	b.String("server")
+	b.String(digest(salt, []byte("server"), nonce, []byte(s.sharedKey)))
+	_, _ = conn.Write(b.Bytes())
+	return ok
+}
+
+func (s *server) next(t *testing.T) []interface{} {
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func testConfig(address string) Config {
+	config := DefaultConfig()
+	config.Address = address
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	return config
+}
+
+var entryTime = time.Unix(1600000000, 42)
+
+func eventTime(t *testing.T, v interface{}) time.Time {
+	ts, ok := v.(msgpack.Ext).Time()
+	assert.True(t, ok)
+	return ts
+}
+
+func TestWriter_forward(t *testing.T) {
+	s := newServer(t, "tcp", "127.0.0.1:0")
+	s.serve(t)
+
+	config := testConfig(s.ln.Addr().String())
+	config.Tag = "app.{logger}"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{LoggerName: "db", Time: entryTime}, nil, []byte(`{"msg":"1","n":2}`+"\n")))
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{LoggerName: "http", Time: entryTime}, nil, []byte("plain\n")))
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{LoggerName: "db", Time: entryTime}, nil, []byte(`{"msg":"3"}`+"\n")))
+	assert.NoError(t, w.Sync())
+
+	msg := s.next(t)
+	assert.Equal(t, "app.db", msg[0])
+	entries := msg[1].([]interface{})
+	assert.Len(t, entries, 2)
+	first := entries[0].([]interface{})
+	assert.True(t, entryTime.Equal(eventTime(t, first[0])))
+	assert.Equal(t, map[string]interface{}{"msg": "1", "n": uint64(2)}, first[1])
+	assert.Equal(t, map[string]interface{}{}, msg[2])
+
+	msg = s.next(t)
+	assert.Equal(t, "app.http", msg[0])
+	assert.Equal(t, map[string]interface{}{"message": "plain"}, msg[1].([]interface{})[0].([]interface{})[1])
+}
+
+func TestWriter_message(t *testing.T) {
+	s := newServer(t, "tcp", "127.0.0.1:0")
+	s.serve(t)
+
+	config := testConfig(s.ln.Addr().String())
+	config.Mode = ModeMessage
+	config.EventTime = false
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"1"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	msg := s.next(t)
+	assert.Len(t, msg, 4)
+	assert.Equal(t, rootTag, msg[0])
+	assert.IsType(t, uint64(0), msg[1])
+	assert.Equal(t, map[string]interface{}{"msg": "1"}, msg[2])
+}
+
+func TestWriter_packedForwardAck(t *testing.T) {
+	dir := t.TempDir()
+	s := newServer(t, "unix", filepath.Join(dir, "fluent.sock"))
+	s.serve(t)
+
+	config := testConfig(s.ln.Addr().String())
+	config.Network = "unix"
+	config.Mode = ModePackedForward
+	config.RequireAck = true
+	config.AckTimeout = time.Second
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{Time: entryTime}, nil, []byte(`{"msg":"1"}`+"\n")))
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{Time: entryTime}, nil, []byte(`{"msg":"2"}`+"\n")))
+	assert.NoError(t, w.Sync())
+
+	msg := s.next(t)
+	assert.Equal(t, rootTag, msg[0])
+	assert.Len(t, msg[2].(map[string]interface{})["chunk"], 24)
+
+	dec := msgpack.NewDecoder(bytes.NewReader(msg[1].([]byte)))
+	for _, expected := range []string{"1", "2"} {
+		v, err := dec.Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"msg": expected}, v.([]interface{})[1])
+	}
+}
+
+func TestWriter_ackTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		// accept and never answer
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	config := testConfig(ln.Addr().String())
+	config.RequireAck = true
+	config.AckTimeout = 50 * time.Millisecond
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"1"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Error(t, w.Sync())
+	assert.Equal(t, 1, w.Buffered())
+}
+
+func TestWriter_sharedKey(t *testing.T) {
+	s := newServer(t, "tcp", "127.0.0.1:0")
+	s.sharedKey = "secret"
+	s.serve(t)
+
+	config := testConfig(s.ln.Addr().String())
+	config.SharedKey = "secret"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"1"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, rootTag, s.next(t)[0])
+
+	config.SharedKey = "wrong"
+	bad, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer bad.Close()
+
+	_, err = bad.Write([]byte(`{"msg":"1"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Error(t, bad.Sync())
+}
+
+func TestWriter_reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := ln.Addr().String()
+	_ = ln.Close()
+
+	w, err := NewWriter(testConfig(address))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"1"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Error(t, w.Sync())
+	assert.Equal(t, 1, w.Buffered())
+
+	s := newServer(t, "tcp", address)
+	s.serve(t)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = w.Write([]byte(`{"msg":"2"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, 0, w.Buffered())
+
+	first := s.next(t)[1].([]interface{})[0].([]interface{})
+	assert.Equal(t, map[string]interface{}{"msg": "1"}, first[1])
+	second := s.next(t)[1].([]interface{})[0].([]interface{})
+	assert.Equal(t, map[string]interface{}{"msg": "2"}, second[1])
+}
@@ -0,0 +1,97 @@
+package fluent
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/khorevaa/logos/internal/msgpack"
+)
+
+// handshake authenticates the connection with the shared key:
+// the server sends HELO, the client answers PING and the server PONG.
+func (w *Writer) handshake(conn net.Conn) error {
+	if w.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(w.timeout))
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+	dec := msgpack.NewDecoder(conn)
+
+	helo, err := readCommand(dec, "HELO", 2)
+	if err != nil {
+		return err
+	}
+	options, _ := helo[1].(map[string]interface{})
+	nonce := bytesValue(options["nonce"])
+	authSalt := bytesValue(options["auth"])
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	var passwordDigest string
+	if len(authSalt) > 0 {
+		passwordDigest = digest(authSalt, []byte(w.username), []byte(w.password))
+	}
+
+	var b msgpack.Buffer
+	b.ArrayHeader(6)
+	b.String("PING")
+	b.String(w.selfHostname)
+	b.Binary(salt)
+	b.String(digest(salt, []byte(w.selfHostname), nonce, []byte(w.sharedKey)))
+	b.String(w.username)
+	b.String(passwordDigest)
+	if _, err := conn.Write(b.Bytes()); err != nil {
+		return err
+	}
+
+	pong, err := readCommand(dec, "PONG", 5)
+	if err != nil {
+		return err
+	}
+	if ok, _ := pong[1].(bool); !ok {
+		return fmt.Errorf("fluent forward handshake: authentication failed: %v", pong[2])
+	}
+	serverHostname := bytesValue(pong[3])
+	if string(bytesValue(pong[4])) != digest(salt, serverHostname, nonce, []byte(w.sharedKey)) {
+		return errors.New("fluent forward handshake: server shared key mismatch")
+	}
+	return nil
+}
+
+func readCommand(dec *msgpack.Decoder, name string, size int) ([]interface{}, error) {
+	v, err := dec.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("fluent forward handshake: %v", err)
+	}
+	cmd, _ := v.([]interface{})
+	if len(cmd) < size || string(bytesValue(cmd[0])) != name {
+		return nil, fmt.Errorf("fluent forward handshake: expected %s, got %v", name, v)
+	}
+	return cmd, nil
+}
+
+// bytesValue returns str and bin values as bytes.
+func bytesValue(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	}
+	return nil
+}
+
+func digest(parts ...[]byte) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,117 @@
+package gelfhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"time"
+
+	"github.com/khorevaa/logos/appender/gelfudp"
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/httpclient"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	httpclient.Config `logos-config:",inline"`
+
+	CompressionType  string `logos-config:"compression_type" logos-validate:"logos.oneof=none gzip zlib"`
+	CompressionLevel int    `logos-config:"compression_level"`
+
+	// Batch groups messages into one newline delimited request. Batches of
+	// more than one message require bulk receiving enabled on the input.
+	Batch batch.Config `logos-config:"batch"`
+}
+
+var defaultConfig = Config{
+	Config:           httpclient.DefaultConfig,
+	CompressionType:  "none",
+	CompressionLevel: gzip.DefaultCompression,
+	Batch: batch.Config{
+		Size:     1,
+		Bytes:    1 << 20,
+		Interval: time.Second,
+	},
+}
+
+var contentEncodings = map[string]string{
+	"gzip": "gzip",
+	"zlib": "deflate",
+}
+
+// Writer posts GELF messages to a Graylog HTTP input.
+type Writer struct {
+	client     *httpclient.Client
+	compressor *gelfudp.Compressor
+	header     http.Header
+	batcher    *batch.Batcher
+}
+
+func New(rawConfig *common.Config) (zapcore.WriteSyncer, error) {
+	config := defaultConfig
+	if err := rawConfig.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return NewWriter(config)
+}
+
+func NewWriter(config Config) (*Writer, error) {
+	client, err := httpclient.New(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := gelfudp.NewCompressor(config.CompressionType, config.CompressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if encoding, ok := contentEncodings[config.CompressionType]; ok {
+		header.Set("Content-Encoding", encoding)
+	}
+
+	w := &Writer{
+		client:     client,
+		compressor: c,
+		header:     header,
+	}
+	w.batcher = batch.New(config.Batch, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	msg := make([]byte, len(p))
+	copy(msg, p)
+	if err := w.batcher.Add(bytes.TrimRight(msg, "\r\n"), len(msg)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync sends the queued messages.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	return w.batcher.Close()
+}
+
+func (w *Writer) send(items []interface{}) error {
+	var body bytes.Buffer
+	for i, item := range items {
+		if i > 0 {
+			body.WriteByte('\n')
+		}
+		body.Write(item.([]byte))
+	}
+
+	_, b, err := w.compressor.Compress(body.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.client.Post(b, w.header)
+	return err
+}
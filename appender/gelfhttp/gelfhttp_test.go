@@ -0,0 +1,155 @@
+package gelfhttp
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGelfHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+url: http://127.0.0.1:12201/gelf
+encoder:
+ gelf:`, false},
+		{"case2", `
+encoder:
+ gelf:`, true},
+		{"case3", `
+url: http://127.0.0.1:12201/gelf
+compression_type: lz4
+encoder:
+ gelf:`, true},
+		{"case4", `
+url: http://127.0.0.1:12201/gelf
+batch:
+  size: 0
+encoder:
+ gelf:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		body, _ = gzip.NewReader(body)
+	}
+	data, _ := ioutil.ReadAll(body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(data))
+
+	status := http.StatusAccepted
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) get() ([]*http.Request, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests, r.bodies
+}
+
+func testConfig(url string) Config {
+	config := defaultConfig
+	config.URL = url
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	return config
+}
+
+func TestWriter_batching(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.CompressionType = "gzip"
+	config.Headers = map[string]string{"Authorization": "Bearer token"}
+	config.Batch.Size = 2
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+
+	for _, msg := range []string{`{"short_message":"1"}`, `{"short_message":"2"}`, `{"short_message":"3"}`} {
+		_, err = w.Write([]byte(msg + "\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Sync())
+	assert.NoError(t, w.Close())
+
+	requests, bodies := r.get()
+	assert.Equal(t, []string{
+		`{"short_message":"1"}` + "\n" + `{"short_message":"2"}`,
+		`{"short_message":"3"}`,
+	}, bodies)
+	assert.Equal(t, "Bearer token", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+}
+
+func TestWriter_retry(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	w, err := NewWriter(testConfig(srv.URL))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"short_message":"retried"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	_, bodies := r.get()
+	assert.Len(t, bodies, 3)
+}
+
+func TestWriter_clientError(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Batch.Size = 10
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"short_message":"invalid"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Error(t, w.Sync())
+
+	_, bodies := r.get()
+	assert.Len(t, bodies, 1)
+}
@@ -0,0 +1,253 @@
+package gelftcp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	Host string           `logos-config:"host"`
+	Port int              `logos-config:"port"`
+	TLS  common.TLSConfig `logos-config:"tls"`
+
+	// PoolSize is the number of connections used to send messages concurrently.
+	PoolSize int `logos-config:"pool_size" logos-validate:"min=1"`
+
+	// Timeout applies to dialing and to each write.
+	Timeout time.Duration `logos-config:"timeout"`
+
+	// BackoffMin and BackoffMax bound the delay between reconnect attempts.
+	BackoffMin time.Duration `logos-config:"backoff_min"`
+	BackoffMax time.Duration `logos-config:"backoff_max"`
+
+	// BufferSize is the number of messages kept while the server is
+	// unreachable. The oldest messages are dropped when it is full.
+	BufferSize int `logos-config:"buffer_size" logos-validate:"min=0"`
+}
+
+var defaultConfig = Config{
+	Host:       "127.0.0.1",
+	Port:       12201,
+	PoolSize:   1,
+	Timeout:    5 * time.Second,
+	BackoffMin: 100 * time.Millisecond,
+	BackoffMax: 30 * time.Second,
+	BufferSize: 1000,
+}
+
+var (
+	ErrNotConnected = errors.New("gelf tcp server is not connected")
+	ErrClosed       = errors.New("gelf tcp writer closed")
+)
+
+// Writer sends null-byte delimited GELF messages over TCP.
+type Writer struct {
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+
+	pool chan *pooledConn
+
+	mu          sync.Mutex
+	backoff     common.Backoff
+	nextAttempt time.Time
+	closed      bool
+
+	buffer *common.MessageQueue
+}
+
+type pooledConn struct {
+	net.Conn
+}
+
+func New(rawConfig *common.Config) (zapcore.WriteSyncer, error) {
+	config := defaultConfig
+	if err := rawConfig.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return NewWriter(config)
+}
+
+func NewWriter(config Config) (*Writer, error) {
+	tlsConfig, err := config.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		address:   fmt.Sprintf("%s:%d", config.Host, config.Port),
+		tlsConfig: tlsConfig,
+		timeout:   config.Timeout,
+		pool:      make(chan *pooledConn, config.PoolSize),
+		backoff: common.Backoff{
+			Min: config.BackoffMin,
+			Max: config.BackoffMax,
+		},
+		buffer: common.NewMessageQueue(config.BufferSize),
+	}
+	for i := 0; i < config.PoolSize; i++ {
+		w.pool <- &pooledConn{}
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	msg := frame(p)
+
+	c := <-w.pool
+	defer w.release(c)
+
+	if w.isClosed() {
+		return 0, ErrClosed
+	}
+	err = w.flush(c)
+	if err == nil {
+		err = w.send(c, msg)
+	}
+	if err != nil && !w.buffer.Push(msg) {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync sends the buffered messages. It fails if the server is still unreachable.
+func (w *Writer) Sync() error {
+	c := <-w.pool
+	defer w.release(c)
+
+	if w.isClosed() {
+		return nil
+	}
+	return w.flush(c)
+}
+
+func (w *Writer) isClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
+}
+
+// release returns the connection to the pool, closing it if the writer was
+// closed while it was in use.
+func (w *Writer) release(c *pooledConn) {
+	if c.Conn != nil && w.isClosed() {
+		_ = c.Close()
+		c.Conn = nil
+	}
+	w.pool <- c
+}
+
+// Close closes the connections, the later writes fail with ErrClosed.
+// A connection in use is closed when its write returns.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	var err error
+	for i := 0; i < cap(w.pool); i++ {
+		c := <-w.pool
+		if c.Conn != nil {
+			if cErr := c.Close(); cErr != nil {
+				err = cErr
+			}
+			c.Conn = nil
+		}
+		w.pool <- c
+	}
+	return err
+}
+
+// Dropped returns the number of messages dropped because the buffer was full.
+func (w *Writer) Dropped() int {
+	return w.buffer.Dropped()
+}
+
+// flush sends the buffered messages in order.
+func (w *Writer) flush(c *pooledConn) error {
+	for {
+		msg, ok := w.buffer.Pop()
+		if !ok {
+			return nil
+		}
+		if err := w.send(c, msg); err != nil {
+			w.buffer.PushFront(msg)
+			return err
+		}
+	}
+}
+
+func (w *Writer) send(c *pooledConn, msg []byte) error {
+	if c.Conn == nil {
+		conn, err := w.dial()
+		if err != nil {
+			return err
+		}
+		c.Conn = conn
+	}
+
+	if w.timeout > 0 {
+		_ = c.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+	if _, err := c.Conn.Write(msg); err != nil {
+		_ = c.Close()
+		c.Conn = nil
+		w.failed()
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) dial() (net.Conn, error) {
+	w.mu.Lock()
+	wait := time.Now().Before(w.nextAttempt)
+	w.mu.Unlock()
+	if wait {
+		return nil, ErrNotConnected
+	}
+
+	dialer := &net.Dialer{Timeout: w.timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", w.address)
+	}
+	if err != nil {
+		w.failed()
+		return nil, err
+	}
+
+	w.mu.Lock()
+	w.backoff.Reset()
+	w.nextAttempt = time.Time{}
+	w.mu.Unlock()
+	return conn, nil
+}
+
+func (w *Writer) failed() {
+	w.mu.Lock()
+	w.nextAttempt = time.Now().Add(w.backoff.Next())
+	w.mu.Unlock()
+}
+
+// frame replaces the line ending of the encoded message with the null byte
+// delimiting GELF TCP frames.
+func frame(p []byte) []byte {
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+	msg := make([]byte, len(p)+1)
+	copy(msg, p)
+	return msg
+}
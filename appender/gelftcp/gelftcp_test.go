@@ -0,0 +1,223 @@
+package gelftcp
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGelfTCP(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+host: 127.0.0.1
+port: 12201
+encoder:
+ gelf:`, false},
+		{"case2", `
+pool_size: 0
+encoder:
+ gelf:`, true},
+		{"case3", `
+tls:
+  enabled: true
+  ca_file: /not/exists.pem
+encoder:
+ gelf:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		_, err = New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+// readFrames accepts a connection and reads n frames from it in background.
+func readFrames(t *testing.T, ln net.Listener, n int) <-chan []string {
+	result := make(chan []string, 1)
+	go func() {
+		defer close(result)
+		conn, err := ln.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		r := bufio.NewReader(conn)
+		var frames []string
+		for i := 0; i < n; i++ {
+			frame, err := r.ReadString(0)
+			assert.NoError(t, err)
+			frames = append(frames, strings.TrimSuffix(frame, "\x00"))
+		}
+		result <- frames
+	}()
+	return result
+}
+
+func testConfig(addr net.Addr) Config {
+	tcpAddr := addr.(*net.TCPAddr)
+	config := defaultConfig
+	config.Host = tcpAddr.IP.String()
+	config.Port = tcpAddr.Port
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	return config
+}
+
+func TestWriter_Write(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	w, err := NewWriter(testConfig(ln.Addr()))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	frames := readFrames(t, ln, 2)
+	_, err = w.Write([]byte(`{"short_message":"first"}` + "\n"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(`{"short_message":"second"}` + "\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`{"short_message":"first"}`,
+		`{"short_message":"second"}`,
+	}, <-frames)
+}
+
+func TestWriter_Close(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	config := testConfig(ln.Addr())
+	config.PoolSize = 2
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+
+	// a connection in use by a write while the writer is closed
+	inUse := <-w.pool
+	inUse.Conn, err = net.Dial("tcp", ln.Addr().String())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	server, err := ln.Accept()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	assert.NoError(t, w.Close())
+	assert.Len(t, w.pool, 1)
+	w.release(inUse)
+	assert.Nil(t, inUse.Conn)
+	assert.Len(t, w.pool, 2)
+
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = server.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+
+	_, err = w.Write([]byte("late\n"))
+	assert.Equal(t, ErrClosed, err)
+	assert.NoError(t, w.Close())
+}
+
+func TestWriter_reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr()
+	_ = ln.Close()
+
+	config := testConfig(addr)
+	config.BufferSize = 2
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	for _, msg := range []string{"1", "2", "3"} {
+		_, err = w.Write([]byte(msg + "\n"))
+		assert.NoError(t, err)
+	}
+	assert.Error(t, w.Sync())
+	assert.Equal(t, 1, w.Dropped())
+
+	ln, err = net.Listen("tcp", addr.String())
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	frames := readFrames(t, ln, 2)
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, []string{"2", "3"}, <-frames)
+}
+
+func TestWriter_noBuffer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr()
+	_ = ln.Close()
+
+	config := testConfig(addr)
+	config.BufferSize = 0
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+
+	_, err = w.Write([]byte("1\n"))
+	assert.Error(t, err)
+}
+
+func TestWriter_tls(t *testing.T) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t)},
+	})
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	config := testConfig(ln.Addr())
+	config.TLS = common.TLSConfig{Enabled: true, InsecureSkipVerify: true}
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	frames := readFrames(t, ln, 1)
+	_, err = w.Write([]byte(`{"short_message":"secure"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"short_message":"secure"}`}, <-frames)
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
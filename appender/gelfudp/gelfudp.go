@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"compress/zlib"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/khorevaa/logos/internal/common"
@@ -19,6 +20,12 @@ type Config struct {
 	Port             int    `logos-config:"port"`
 	CompressionType  string `logos-config:"compression_type" logos-validate:"logos.oneof=none gzip zlib"`
 	CompressionLevel int    `logos-config:"compression_level"`
+
+	// OversizePolicy handles messages larger than MaxMessageSize after
+	// compression: error, truncate the full_message, drop or fallback
+	// to FallbackAppender.
+	OversizePolicy   string `logos-config:"oversize_policy" logos-validate:"logos.oneof=error truncate drop fallback"`
+	FallbackAppender string `logos-config:"fallback_appender"`
 }
 
 var defaultConfig = Config{
@@ -26,8 +33,16 @@ var defaultConfig = Config{
 	Port:             12201,
 	CompressionType:  "gzip",
 	CompressionLevel: gzip.DefaultCompression,
+	OversizePolicy:   OversizeError,
 }
 
+const (
+	OversizeError    = "error"
+	OversizeTruncate = "truncate"
+	OversizeDrop     = "drop"
+	OversizeFallback = "fallback"
+)
+
 const (
 	MaxDatagramSize = 1420
 	HeadSize        = 12
@@ -50,11 +65,7 @@ type UDPSender struct {
 }
 
 func NewUDPSender(address string) (*UDPSender, error) {
-	ip, err := GuessIP()
-	if err != nil {
-		return nil, err
-	}
-	id := NewDefaultIdGenerator(ip)
+	id := NewDefaultIdGenerator(0)
 	raddr, err := net.ResolveUDPAddr("udp", address)
 	if err != nil {
 		return nil, err
@@ -163,24 +174,122 @@ func (c *Compressor) Compress(buf []byte) (int, []byte, error) {
 type Writer struct {
 	sender     *UDPSender
 	compressor *Compressor
+
+	oversizePolicy   string
+	fallbackAppender string
+	fallback         zapcore.Core
 }
 
 func (w *Writer) Write(p []byte) (n int, err error) {
-	n, b, err := w.compressor.Compress(p)
-	if err != nil {
+	if err := w.write(p, nil); err != nil {
 		return 0, err
 	}
-	if err := w.sender.Send(b); err != nil {
-		return 0, err
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	return w.write(p, func() error {
+		return w.fallback.Write(ent, fields)
+	})
+}
+
+func (w *Writer) Sync() error {
+	if w.fallback != nil {
+		return w.fallback.Sync()
+	}
+	return nil
+}
+
+// FallbackAppender returns the name of the appender receiving oversize entries.
+func (w *Writer) FallbackAppender() string {
+	return w.fallbackAppender
+}
+
+// SetFallback sets the core receiving oversize entries.
+func (w *Writer) SetFallback(core zapcore.Core) {
+	w.fallback = core
+}
+
+func (w *Writer) write(p []byte, fallback func() error) error {
+	_, b, err := w.compressor.Compress(p)
+	if err != nil {
+		return err
+	}
+
+	if len(b) > MaxMessageSize {
+		switch w.oversizePolicy {
+		case OversizeDrop:
+			return nil
+		case OversizeTruncate:
+			if b, err = w.truncate(p); err != nil {
+				return err
+			}
+		case OversizeFallback:
+			if fallback == nil || w.fallback == nil {
+				return ErrTooLargeMessageSize
+			}
+			return fallback()
+		default:
+			return ErrTooLargeMessageSize
+		}
 	}
-	return n, nil
+
+	return w.sender.Send(b)
 }
 
+// truncate shortens the full_message of the GELF message p until the
+// compressed message fits into MaxMessageSize.
+func (w *Writer) truncate(p []byte) ([]byte, error) {
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(p, &message); err != nil {
+		return nil, err
+	}
+
+	var fullMessage string
+	if raw, ok := message[fullMessageKey]; ok {
+		if err := json.Unmarshal(raw, &fullMessage); err != nil {
+			return nil, err
+		}
+	}
+
+	for size := len(fullMessage) / 2; ; size /= 2 {
+		if size > 0 {
+			raw, _ := json.Marshal(fullMessage[:size] + truncatedSuffix)
+			message[fullMessageKey] = raw
+		} else {
+			delete(message, fullMessageKey)
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		_, b, err := w.compressor.Compress(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) <= MaxMessageSize {
+			return b, nil
+		}
+		if size == 0 {
+			return nil, ErrTooLargeMessageSize
+		}
+	}
+}
+
+const (
+	fullMessageKey  = "full_message"
+	truncatedSuffix = "...(truncated)"
+)
+
 func New(rawConfig *common.Config) (zapcore.WriteSyncer, error) {
 	config := defaultConfig
 	if err := rawConfig.Unpack(&config); err != nil {
 		return nil, err
 	}
+	if config.OversizePolicy == OversizeFallback && len(config.FallbackAppender) == 0 {
+		return nil, errors.New("oversize policy fallback requires fallback_appender")
+	}
 	c, err := NewCompressor(config.CompressionType, config.CompressionLevel)
 	if err != nil {
 		return nil, err
@@ -189,5 +298,13 @@ func New(rawConfig *common.Config) (zapcore.WriteSyncer, error) {
 	if err != nil {
 		return nil, err
 	}
-	return zapcore.AddSync(&Writer{s, c}), nil
+	w := &Writer{
+		sender:         s,
+		compressor:     c,
+		oversizePolicy: config.OversizePolicy,
+	}
+	if config.OversizePolicy == OversizeFallback {
+		w.fallbackAppender = config.FallbackAppender
+	}
+	return w, nil
 }
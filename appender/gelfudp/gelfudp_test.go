@@ -0,0 +1,146 @@
+package gelfudp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewGelfUDP(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+host: 127.0.0.1
+encoder:
+ gelf:`, false},
+		{"case2", `
+oversize_policy: split
+encoder:
+ gelf:`, true},
+		{"case3", `
+oversize_policy: fallback
+encoder:
+ gelf:`, true},
+		{"case4", `
+oversize_policy: fallback
+fallback_appender: FILE
+encoder:
+ gelf:`, false},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		_, err = New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+func newTestWriter(t *testing.T, policy string) (*Writer, net.PacketConn) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"port":              addr.Port,
+		"compression_type":  "none",
+		"oversize_policy":   policy,
+		"fallback_appender": "FALLBACK",
+	})
+	w, err := New(cfg)
+	assert.NoError(t, err)
+	return w.(*Writer), conn
+}
+
+// readMessage reads the datagrams of one message and joins its chunks.
+func readMessage(t *testing.T, conn net.PacketConn) []byte {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, MaxDatagramSize)
+
+	var message []byte
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		if !bytes.HasPrefix(buf[:n], Magic) {
+			return append([]byte(nil), buf[:n]...)
+		}
+		message = append(message, buf[HeadSize:n]...)
+		if buf[10] == buf[11]-1 {
+			return message
+		}
+	}
+}
+
+func oversizeMessage() []byte {
+	message, _ := json.Marshal(map[string]string{
+		"short_message": "short",
+		"full_message":  strings.Repeat("x", MaxMessageSize),
+	})
+	return message
+}
+
+func TestWriter_oversizeError(t *testing.T) {
+	w, _ := newTestWriter(t, OversizeError)
+	_, err := w.Write(oversizeMessage())
+	assert.Equal(t, ErrTooLargeMessageSize, err)
+}
+
+func TestWriter_oversizeDrop(t *testing.T) {
+	w, conn := newTestWriter(t, OversizeDrop)
+	_, err := w.Write(oversizeMessage())
+	assert.NoError(t, err)
+
+	_, err = w.Write([]byte(`{"short_message":"next"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"short_message":"next"}`, string(readMessage(t, conn)))
+}
+
+func TestWriter_oversizeTruncate(t *testing.T) {
+	w, conn := newTestWriter(t, OversizeTruncate)
+	_, err := w.Write(oversizeMessage())
+	assert.NoError(t, err)
+
+	var message map[string]string
+	assert.NoError(t, json.Unmarshal(readMessage(t, conn), &message))
+	assert.Equal(t, "short", message["short_message"])
+	assert.True(t, strings.HasSuffix(message["full_message"], truncatedSuffix))
+	assert.Less(t, len(message["full_message"]), MaxMessageSize)
+}
+
+type fallbackCore struct {
+	zapcore.Core
+	entries []zapcore.Entry
+}
+
+func (c *fallbackCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	c.entries = append(c.entries, ent)
+	return nil
+}
+
+func TestWriter_oversizeFallback(t *testing.T) {
+	w, _ := newTestWriter(t, OversizeFallback)
+	assert.Equal(t, "FALLBACK", w.FallbackAppender())
+
+	core := &fallbackCore{}
+	w.SetFallback(core)
+
+	ent := zapcore.Entry{Message: "short"}
+	assert.NoError(t, w.WriteEntry(ent, nil, oversizeMessage()))
+	assert.Equal(t, []zapcore.Entry{ent}, core.entries)
+
+	_, err := w.Write(oversizeMessage())
+	assert.Equal(t, ErrTooLargeMessageSize, err)
+}
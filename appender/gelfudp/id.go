@@ -1,11 +1,14 @@
 package gelfudp
 
 import (
+	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,21 +16,34 @@ type IdGenerator interface {
 	NextId() uint64
 }
 
+// DefaultIdGenerator combines a random prefix with a counter, so ids are
+// unique within the process and unlikely to collide between hosts.
 type DefaultIdGenerator struct {
-	ip uint64
+	prefix  uint64
+	counter uint32
 }
 
+// NewDefaultIdGenerator returns a generator with a random prefix, the ip
+// is mixed into it, e.g. to tell apart hosts with a weak random source.
 func NewDefaultIdGenerator(ip uint64) *DefaultIdGenerator {
-	return &DefaultIdGenerator{ip}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		binary.BigEndian.PutUint64(b[:], uint64(time.Now().UnixNano()))
+	}
+	return &DefaultIdGenerator{
+		prefix:  (binary.BigEndian.Uint64(b[:]) ^ ip) << 32,
+		counter: binary.BigEndian.Uint32(b[4:]),
+	}
 }
 
 func (g *DefaultIdGenerator) NextId() uint64 {
-	timestamp := uint64(time.Now().Nanosecond())
-	timestamp = timestamp << 32
-	timestamp = timestamp >> 32
-	return (g.ip << 32) | timestamp
+	return g.prefix | uint64(atomic.AddUint32(&g.counter, 1))
 }
 
+// GuessIP returns the first IPv4 address of the host name as an integer.
+//
+// Deprecated: the ids of NewDefaultIdGenerator are random, the ip is not
+// needed to tell hosts apart.
 func GuessIP() (uint64, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -0,0 +1,37 @@
+package gelfudp
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultIdGenerator_NextId(t *testing.T) {
+	g := NewDefaultIdGenerator(0)
+
+	const goroutines, perGoroutine = 8, 1000
+	ids := make(chan uint64, goroutines*perGoroutine)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				ids <- g.NextId()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[uint64]struct{}, goroutines*perGoroutine)
+	for id := range ids {
+		_, dup := seen[id]
+		assert.False(t, dup, "duplicated id %d", id)
+		seen[id] = struct{}{}
+	}
+
+	assert.NotEqual(t, g.NextId()>>32, NewDefaultIdGenerator(0).NextId()>>32)
+}
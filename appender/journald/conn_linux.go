@@ -0,0 +1,89 @@
+package journald
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// conn sends datagrams to the journal socket from an unbound socket,
+// so it keeps working across journald restarts.
+type conn struct {
+	addr *net.UnixAddr
+	conn *net.UnixConn
+}
+
+func dial(socket string) (*conn, error) {
+	c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &conn{
+		addr: &net.UnixAddr{Name: socket, Net: "unixgram"},
+		conn: c,
+	}, nil
+}
+
+// send writes the entry as one datagram. Entries too large for a datagram
+// are written to a sealed memfd, or a deleted temporary file, whose
+// descriptor is sent instead.
+func (c *conn) send(data []byte) error {
+	_, _, err := c.conn.WriteMsgUnix(data, nil, c.addr)
+	if err == nil || !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+
+	f, err := memfd(data)
+	if err != nil {
+		if f, err = tempFile(data); err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+
+	_, _, err = c.conn.WriteMsgUnix(nil, unix.UnixRights(int(f.Fd())), c.addr)
+	return err
+}
+
+func (c *conn) close() error {
+	return c.conn.Close()
+}
+
+func memfd(data []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("logos-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "logos-journal")
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// tempFile writes data to an unlinked file, journald accepts them on
+// kernels without memfd.
+func tempFile(data []byte) (*os.File, error) {
+	f, err := ioutil.TempFile("/dev/shm", "logos-journal-")
+	if err != nil {
+		if f, err = ioutil.TempFile("", "logos-journal-"); err != nil {
+			return nil, err
+		}
+	}
+	_ = os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,239 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultSocket = "/run/systemd/journal/socket"
+
+type Config struct {
+	// Socket is the journald native protocol socket.
+	Socket string `logos-config:"socket" logos-validate:"required"`
+	// SyslogIdentifier is used for entries without a logger name, it
+	// defaults to the executable name.
+	SyslogIdentifier string `logos-config:"syslog_identifier"`
+	// Message is the source of the MESSAGE field: the entry message or
+	// the encoded entry.
+	Message string `logos-config:"message" logos-validate:"logos.oneof=message encoded"`
+	// FieldPrefix is prepended to the names of the entry fields.
+	FieldPrefix string `logos-config:"field_prefix"`
+	// Fields are added to every entry.
+	Fields map[string]string `logos-config:"fields"`
+}
+
+var (
+	defaultConfig = Config{
+		Socket:  defaultSocket,
+		Message: MessageText,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+const (
+	MessageText    = "message"
+	MessageEncoded = "encoded"
+)
+
+// priorities maps the levels to the syslog priorities journald expects.
+var priorities = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "7",
+	zapcore.InfoLevel:   "6",
+	zapcore.WarnLevel:   "4",
+	zapcore.ErrorLevel:  "3",
+	zapcore.DPanicLevel: "2",
+	zapcore.PanicLevel:  "1",
+	zapcore.FatalLevel:  "0",
+}
+
+// Writer sends entries to journald with the native protocol.
+type Writer struct {
+	conn       *conn
+	identifier string
+	encoded    bool
+	prefix     string
+	fields     []field
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	c, err := dial(cfg.Socket)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		conn:       c,
+		identifier: cfg.SyslogIdentifier,
+		encoded:    cfg.Message == MessageEncoded,
+		prefix:     cfg.FieldPrefix,
+	}
+	if len(w.identifier) == 0 {
+		w.identifier = filepath.Base(os.Args[0])
+	}
+	for name, value := range cfg.Fields {
+		w.fields = append(w.fields, field{userFieldName(name), value})
+	}
+	return w, nil
+}
+
+// Write sends p as the MESSAGE of an entry with the info priority.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	var b bytes.Buffer
+	w.header(&b, string(bytes.TrimRight(p, "\r\n")), priorities[zapcore.InfoLevel], w.identifier)
+	if err := w.conn.send(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	message := ent.Message
+	if w.encoded {
+		message = string(bytes.TrimRight(p, "\r\n"))
+	}
+	identifier := ent.LoggerName
+	if len(identifier) == 0 {
+		identifier = w.identifier
+	}
+
+	var b bytes.Buffer
+	w.header(&b, message, priorities[ent.Level], identifier)
+	if ent.Caller.Defined {
+		writeField(&b, "CODE_FILE", ent.Caller.File)
+		writeField(&b, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if len(ent.Caller.Function) > 0 {
+			writeField(&b, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if len(ent.Stack) > 0 {
+		writeField(&b, w.prefix+"STACKTRACE", ent.Stack)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	for key, value := range enc.Fields {
+		writeField(&b, userFieldName(w.prefix+key), formatValue(value))
+	}
+
+	return w.conn.send(b.Bytes())
+}
+
+func (w *Writer) header(b *bytes.Buffer, message, priority, identifier string) {
+	writeField(b, "MESSAGE", message)
+	writeField(b, "PRIORITY", priority)
+	writeField(b, "SYSLOG_IDENTIFIER", identifier)
+	for _, f := range w.fields {
+		writeField(b, f.name, f.value)
+	}
+}
+
+func (w *Writer) Sync() error {
+	return nil
+}
+
+func (w *Writer) Close() error {
+	return w.conn.close()
+}
+
+// writeField appends a field in the native protocol format. Values with
+// newlines are written in the binary form: the name, a newline, the
+// little endian 64 bit size and the value.
+func writeField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if strings.IndexByte(value, '\n') < 0 {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+
+	b.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b.Write(size[:])
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// reservedFields are written by the Writer itself, the entry and config
+// fields converted to one of them are renamed with a FIELD_ prefix.
+var reservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+	"STACKTRACE":        true,
+}
+
+// userFieldName converts name like FieldName and renames the reserved names.
+func userFieldName(name string) string {
+	field := FieldName(name)
+	if reservedFields[field] {
+		return "FIELD_" + field
+	}
+	return field
+}
+
+// FieldName converts name to a valid journal field name: upper-cased
+// letters, digits and underscores, not starting with an underscore or a
+// digit and at most 64 characters long.
+func FieldName(name string) string {
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name) && len(b) < 64; i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			b = append(b, c-'a'+'A')
+		case c >= 'A' && c <= 'Z':
+			b = append(b, c)
+		case c >= '0' && c <= '9' && len(b) > 0:
+			b = append(b, c)
+		case len(b) > 0:
+			b = append(b, '_')
+		}
+	}
+	if len(b) == 0 {
+		return "FIELD"
+	}
+	return string(b)
+}
+
+// formatValue formats a value added to a zapcore.MapObjectEncoder, arrays
+// and objects as JSON.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}, map[string]interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return common.FormatValue(value)
+}
@@ -0,0 +1,221 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/unix"
+)
+
+func TestNewJournald(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+encoder:
+ console:`, false},
+		{"case2", `
+socket: /tmp/journal.sock
+message: encoded
+field_prefix: app_
+encoder:
+ json:`, false},
+		{"case3", `
+message: fields
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+// journal is a stand-in for the journald socket.
+type journal struct {
+	conn *net.UnixConn
+	path string
+}
+
+func newJournal(t *testing.T) *journal {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &journal{conn: conn, path: path}
+}
+
+// read receives an entry, reading it from the passed descriptor if any,
+// and parses its fields.
+func (j *journal) read(t *testing.T) map[string]string {
+	_ = j.conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1<<16)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := j.conn.ReadMsgUnix(buf, oob)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data := buf[:n]
+
+	if oobn > 0 {
+		msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		assert.NoError(t, err)
+		fds, err := unix.ParseUnixRights(&msgs[0])
+		assert.NoError(t, err)
+		f := os.NewFile(uintptr(fds[0]), "entry")
+		defer f.Close()
+		_, _ = f.Seek(0, 0)
+		data, err = ioutil.ReadAll(f)
+		assert.NoError(t, err)
+	}
+	return parseEntry(t, data)
+}
+
+func parseEntry(t *testing.T, data []byte) map[string]string {
+	fields := map[string]string{}
+	for len(data) > 0 {
+		nl := bytes.IndexByte(data, '\n')
+		line := data[:nl]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			data = data[nl+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[nl+1:])
+		value := data[nl+9 : nl+9+int(size)]
+		fields[string(line)] = string(value)
+		assert.Equal(t, byte('\n'), data[nl+9+int(size)])
+		data = data[nl+10+int(size):]
+	}
+	return fields
+}
+
+func TestWriter_entry(t *testing.T) {
+	j := newJournal(t)
+
+	config := DefaultConfig()
+	config.Socket = j.path
+	config.Fields = map[string]string{"env": "prod"}
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ent := zapcore.Entry{
+		LoggerName: "http",
+		Level:      zapcore.WarnLevel,
+		Message:    "slow\nrequest",
+		Caller:     zapcore.NewEntryCaller(0, "main.go", 42, true),
+	}
+	fields := []zapcore.Field{zap.String("request.id", "abc"), zap.Int("status", 200)}
+	assert.NoError(t, w.WriteEntry(ent, fields, []byte("encoded\n")))
+
+	assert.Equal(t, map[string]string{
+		"MESSAGE":           "slow\nrequest",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "http",
+		"ENV":               "prod",
+		"CODE_FILE":         "main.go",
+		"CODE_LINE":         "42",
+		"REQUEST_ID":        "abc",
+		"STATUS":            "200",
+	}, j.read(t))
+
+	_, err = w.Write([]byte("plain\n"))
+	assert.NoError(t, err)
+	entry := j.read(t)
+	assert.Equal(t, "plain", entry["MESSAGE"])
+	assert.Equal(t, "6", entry["PRIORITY"])
+	assert.Equal(t, filepath.Base(os.Args[0]), entry["SYSLOG_IDENTIFIER"])
+}
+
+func TestWriter_encodedMessage(t *testing.T) {
+	j := newJournal(t)
+
+	config := DefaultConfig()
+	config.Socket = j.path
+	config.Message = MessageEncoded
+	config.FieldPrefix = "app_"
+	config.SyslogIdentifier = "demo"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed"}
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("user", "bob")}, []byte(`{"msg":"failed"}`+"\n")))
+
+	entry := j.read(t)
+	assert.Equal(t, `{"msg":"failed"}`, entry["MESSAGE"])
+	assert.Equal(t, "demo", entry["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "bob", entry["APP_USER"])
+}
+
+func TestWriter_largeEntry(t *testing.T) {
+	j := newJournal(t)
+
+	config := DefaultConfig()
+	config.Socket = j.path
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	// larger than the maximum datagram size
+	message := strings.Repeat("x", 1<<20)
+	done := make(chan map[string]string, 1)
+	go func() { done <- j.read(t) }()
+
+	assert.NoError(t, w.WriteEntry(zapcore.Entry{Message: message}, nil, nil))
+	entry := <-done
+	assert.Equal(t, message, entry["MESSAGE"])
+}
+
+func TestWriter_reservedFields(t *testing.T) {
+	j := newJournal(t)
+
+	config := DefaultConfig()
+	config.Socket = j.path
+	config.Fields = map[string]string{"priority": "high"}
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ent := zapcore.Entry{LoggerName: "http", Level: zapcore.InfoLevel, Message: "done"}
+	fields := []zapcore.Field{
+		zap.String("message", "user message"),
+		zap.String("syslog_identifier", "other"),
+		zap.String("code.line", "7"),
+		zap.String("messages", "kept"),
+	}
+	assert.NoError(t, w.WriteEntry(ent, fields, []byte("encoded\n")))
+
+	assert.Equal(t, map[string]string{
+		"MESSAGE":                 "done",
+		"PRIORITY":                "6",
+		"SYSLOG_IDENTIFIER":       "http",
+		"FIELD_PRIORITY":          "high",
+		"FIELD_MESSAGE":           "user message",
+		"FIELD_SYSLOG_IDENTIFIER": "other",
+		"FIELD_CODE_LINE":         "7",
+		"MESSAGES":                "kept",
+	}, j.read(t))
+}
@@ -0,0 +1,24 @@
+package journald
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldName(t *testing.T) {
+	assert.Equal(t, "REQUEST_ID", FieldName("request.id"))
+	assert.Equal(t, "USER", FieldName("_user"))
+	assert.Equal(t, "A1", FieldName("1a1"))
+	assert.Equal(t, "FIELD", FieldName("..."))
+	assert.Len(t, FieldName(string(bytes.Repeat([]byte("x"), 100))), 64)
+}
+
+func TestWriteField(t *testing.T) {
+	var b bytes.Buffer
+	writeField(&b, "MESSAGE", "hello")
+	writeField(&b, "STACK", "a\nb")
+
+	assert.Equal(t, "MESSAGE=hello\nSTACK\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n", b.String())
+}
@@ -0,0 +1,291 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/httpclient"
+	"github.com/khorevaa/logos/internal/proto"
+	"go.uber.org/zap/zapcore"
+)
+
+const pushPath = "/loki/api/v1/push"
+
+type Config struct {
+	// Config.URL is the Loki server, the push path is appended if missing.
+	httpclient.Config `logos-config:",inline"`
+
+	// Format of the push request, protobuf (snappy compressed) or json.
+	Format string `logos-config:"format" logos-validate:"logos.oneof=protobuf json"`
+
+	// Labels are static labels added to every stream.
+	Labels map[string]string `logos-config:"labels"`
+	// LabelFields are the fields copied into stream labels.
+	LabelFields []string `logos-config:"label_fields"`
+	// LoggerLabel and LevelLabel name the labels for the logger name and
+	// the level. Empty values disable the label.
+	LoggerLabel string `logos-config:"logger_label"`
+	LevelLabel  string `logos-config:"level_label"`
+
+	// TenantID is sent as X-Scope-OrgID header.
+	TenantID    string `logos-config:"tenant_id"`
+	Username    string `logos-config:"username"`
+	Password    string `logos-config:"password"`
+	BearerToken string `logos-config:"bearer_token"`
+
+	Batch batch.Config `logos-config:"batch"`
+}
+
+var (
+	defaultConfig = Config{
+		Config:      httpclient.DefaultConfig,
+		Format:      FormatProtobuf,
+		LoggerLabel: "logger",
+		LevelLabel:  "level",
+		Batch: batch.Config{
+			Size:     1000,
+			Bytes:    1 << 20,
+			Interval: time.Second,
+		},
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+const (
+	FormatProtobuf = "protobuf"
+	FormatJSON     = "json"
+)
+
+// Writer pushes entries to Grafana Loki.
+type Writer struct {
+	client  *httpclient.Client
+	header  http.Header
+	format  string
+	batcher *batch.Batcher
+
+	labels      map[string]string
+	labelFields []string
+	loggerLabel string
+	levelLabel  string
+}
+
+type entry struct {
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if len(cfg.URL) > 0 && !strings.HasSuffix(cfg.URL, pushPath) {
+		cfg.URL = strings.TrimSuffix(cfg.URL, "/") + pushPath
+	}
+	client, err := httpclient.New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	switch cfg.Format {
+	case FormatJSON:
+		header.Set("Content-Type", "application/json")
+	default:
+		header.Set("Content-Type", "application/x-protobuf")
+		header.Set("Content-Encoding", "snappy")
+	}
+	if len(cfg.TenantID) > 0 {
+		header.Set("X-Scope-OrgID", cfg.TenantID)
+	}
+	switch {
+	case len(cfg.BearerToken) > 0:
+		header.Set("Authorization", "Bearer "+cfg.BearerToken)
+	case len(cfg.Username) > 0:
+		auth := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+
+	w := &Writer{
+		client:      client,
+		header:      header,
+		format:      cfg.Format,
+		labels:      map[string]string{},
+		labelFields: cfg.LabelFields,
+		loggerLabel: sanitizeLabelName(cfg.LoggerLabel),
+		levelLabel:  sanitizeLabelName(cfg.LevelLabel),
+	}
+	for name, value := range cfg.Labels {
+		w.labels[sanitizeLabelName(name)] = value
+	}
+	w.batcher = batch.New(cfg.Batch, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.add(time.Now(), w.labels, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	labels := make(map[string]string, len(w.labels)+len(w.labelFields)+2)
+	for name, value := range w.labels {
+		labels[name] = value
+	}
+	if len(w.loggerLabel) > 0 && len(ent.LoggerName) > 0 {
+		labels[w.loggerLabel] = ent.LoggerName
+	}
+	if len(w.levelLabel) > 0 {
+		labels[w.levelLabel] = ent.Level.String()
+	}
+	for name, value := range common.FieldValues(fields, w.labelFields...) {
+		labels[sanitizeLabelName(name)] = value
+	}
+	return w.add(ent.Time, labels, p)
+}
+
+// Sync pushes the queued entries.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	return w.batcher.Close()
+}
+
+func (w *Writer) add(t time.Time, labels map[string]string, p []byte) error {
+	line := string(bytes.TrimRight(p, "\r\n"))
+	return w.batcher.Add(&entry{labels: labels, time: t, line: line}, len(line))
+}
+
+type stream struct {
+	labels  map[string]string
+	entries []*entry
+}
+
+func (w *Writer) send(items []interface{}) error {
+	var (
+		streams []*stream
+		index   = map[string]*stream{}
+	)
+	for _, item := range items {
+		e := item.(*entry)
+		key := formatLabels(e.labels)
+		s, ok := index[key]
+		if !ok {
+			s = &stream{labels: e.labels}
+			index[key] = s
+			streams = append(streams, s)
+		}
+		s.entries = append(s.entries, e)
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	switch w.format {
+	case FormatJSON:
+		body, err = encodeJSON(streams)
+	default:
+		body = snappy.Encode(nil, encodeProtobuf(streams))
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.client.Post(body, w.header)
+	return err
+}
+
+func encodeJSON(streams []*stream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+
+	for _, s := range streams {
+		js := jsonStream{Stream: s.labels, Values: make([][2]string, 0, len(s.entries))}
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+		}
+		request.Streams = append(request.Streams, js)
+	}
+	return json.Marshal(request)
+}
+
+// encodeProtobuf encodes the logproto.PushRequest message.
+func encodeProtobuf(streams []*stream) []byte {
+	var b proto.Buffer
+	for _, s := range streams {
+		b.Message(1, func(b *proto.Buffer) {
+			b.String(1, formatLabels(s.labels))
+			for _, e := range s.entries {
+				b.Message(2, func(b *proto.Buffer) {
+					b.Message(1, func(b *proto.Buffer) {
+						b.Int64(1, e.time.Unix())
+						b.Int64(2, int64(e.time.Nanosecond()))
+					})
+					b.String(2, e.line)
+				})
+			}
+		})
+	}
+	return b.Bytes()
+}
+
+// formatLabels formats labels as a Prometheus label set: {a="1", b="2"}.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(labels[name]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// sanitizeLabelName replaces the characters not allowed in label names with '_'.
+func sanitizeLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
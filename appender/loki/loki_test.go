@@ -0,0 +1,162 @@
+package loki
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLoki(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+url: http://127.0.0.1:3100
+encoder:
+ json:`, false},
+		{"case2", `
+labels:
+  app: demo
+encoder:
+ json:`, true},
+		{"case3", `
+url: http://127.0.0.1:3100
+format: xml
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+type pushRequest struct {
+	header http.Header
+	path   string
+	body   []byte
+}
+
+type server struct {
+	mu       sync.Mutex
+	requests []pushRequest
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, pushRequest{r.Header, r.URL.Path, body})
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestWriter(t *testing.T, cfg Config) (*Writer, *server) {
+	s := &server{}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	cfg.URL = srv.URL
+	w, err := NewWriter(cfg)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	return w, s
+}
+
+var entryTime = time.Unix(1600000000, 42)
+
+func TestWriter_json(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Format = FormatJSON
+	cfg.Labels = map[string]string{"app": "demo", "k8s.namespace": "prod"}
+	cfg.LabelFields = []string{"tenant"}
+	cfg.TenantID = "team-a"
+	cfg.Username = "user"
+	cfg.Password = "secret"
+	w, s := newTestWriter(t, cfg)
+
+	ent := zapcore.Entry{LoggerName: "svc", Level: zapcore.InfoLevel, Time: entryTime}
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("tenant", "acme"), zap.Int("n", 1)}, []byte("first\n")))
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("tenant", "other")}, []byte("second\n")))
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("tenant", "acme")}, []byte("third\n")))
+	assert.NoError(t, w.Sync())
+
+	assert.Len(t, s.requests, 1)
+	req := s.requests[0]
+	assert.Equal(t, pushPath, req.path)
+	assert.Equal(t, "team-a", req.header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", req.header.Get("Authorization"))
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+
+	var body struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	assert.NoError(t, json.Unmarshal(req.body, &body))
+	assert.Len(t, body.Streams, 2)
+	assert.Equal(t, map[string]string{
+		"app":           "demo",
+		"k8s_namespace": "prod",
+		"logger":        "svc",
+		"level":         "info",
+		"tenant":        "acme",
+	}, body.Streams[0].Stream)
+	assert.Equal(t, [][2]string{
+		{"1600000000000000042", "first"},
+		{"1600000000000000042", "third"},
+	}, body.Streams[0].Values)
+	assert.Equal(t, "other", body.Streams[1].Stream["tenant"])
+}
+
+func TestWriter_protobuf(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LevelLabel = ""
+	cfg.BearerToken = "token"
+	w, s := newTestWriter(t, cfg)
+
+	ent := zapcore.Entry{LoggerName: "svc", Level: zapcore.InfoLevel, Time: entryTime}
+	assert.NoError(t, w.WriteEntry(ent, nil, []byte("hello\n")))
+	assert.NoError(t, w.Sync())
+
+	assert.Len(t, s.requests, 1)
+	req := s.requests[0]
+	assert.Equal(t, "Bearer token", req.header.Get("Authorization"))
+	assert.Equal(t, "snappy", req.header.Get("Content-Encoding"))
+
+	body, err := snappy.Decode(nil, req.body)
+	assert.NoError(t, err)
+
+	labels := `{logger="svc"}`
+	expected := []byte{0x0a, byte(2 + len(labels) + 2 + 17), 0x0a, byte(len(labels))}
+	expected = append(expected, labels...)
+	expected = append(expected, 0x12, 17,
+		0x0a, 0x08, 0x08, 0x80, 0xa0, 0xf8, 0xfa, 0x05, 0x10, 0x2a,
+		0x12, 0x05)
+	expected = append(expected, "hello"...)
+	assert.Equal(t, expected, body)
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, `{a="1", b="say \"hi\""}`, formatLabels(map[string]string{"b": `say "hi"`, "a": "1"}))
+	assert.Equal(t, "{}", formatLabels(nil))
+	assert.Equal(t, "_abc_x", sanitizeLabelName("1abc.x"))
+}
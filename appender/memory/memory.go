@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	// Size is the number of the most recent entries kept in memory.
+	Size int `logos-config:"size" logos-validate:"min=1"`
+
+	// DumpFile is the file the entries are dumped to on Fatal/Panic entries
+	// or on DumpSignal. Automatic dumps are disabled if empty.
+	DumpFile string `logos-config:"dump_file"`
+
+	// DumpOnFatal dumps the entries to DumpFile after writing an entry
+	// with Panic or Fatal level.
+	DumpOnFatal bool `logos-config:"dump_on_fatal"`
+
+	// DumpSignal is the signal name (e.g. SIGUSR1) that dumps the entries
+	// to DumpFile.
+	DumpSignal string `logos-config:"dump_signal"`
+}
+
+var (
+	defaultConfig = Config{
+		Size:        1000,
+		DumpOnFatal: true,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+// Memory is a fixed-size ring of encoded entries.
+type Memory struct {
+	mu      sync.Mutex
+	entries [][]byte
+	next    int
+	full    bool
+
+	dumpFile    string
+	dumpOnFatal bool
+	signals     chan os.Signal
+	done        chan struct{}
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	m := NewMemory(cfg.Size)
+	m.dumpFile = cfg.DumpFile
+	m.dumpOnFatal = cfg.DumpOnFatal
+
+	if len(cfg.DumpSignal) > 0 && len(cfg.DumpFile) > 0 {
+		sig, err := common.ParseSignal(cfg.DumpSignal)
+		if err != nil {
+			return nil, err
+		}
+		m.signals = make(chan os.Signal, 1)
+		m.done = make(chan struct{})
+		signal.Notify(m.signals, sig)
+		go m.handleSignals(m.signals, m.done)
+	}
+	return m, nil
+}
+
+// NewMemory creates a ring holding up to size entries.
+func NewMemory(size int) *Memory {
+	return &Memory{
+		entries: make([][]byte, size),
+	}
+}
+
+func (m *Memory) Write(p []byte) (n int, err error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	m.mu.Lock()
+	m.entries[m.next] = entry
+	m.next++
+	if m.next == len(m.entries) {
+		m.next = 0
+		m.full = true
+	}
+	m.mu.Unlock()
+
+	return len(p), nil
+}
+
+func (m *Memory) WriteEntry(ent zapcore.Entry, _ []zapcore.Field, p []byte) error {
+	if _, err := m.Write(p); err != nil {
+		return err
+	}
+	if m.dumpOnFatal && len(m.dumpFile) > 0 && ent.Level >= zapcore.PanicLevel {
+		return m.DumpFile(m.dumpFile)
+	}
+	return nil
+}
+
+func (m *Memory) Sync() error {
+	return nil
+}
+
+// Dump writes the kept entries to w, oldest first.
+func (m *Memory) Dump(w io.Writer) error {
+	for _, entry := range m.Entries() {
+		if _, err := w.Write(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpFile writes the kept entries to the file, replacing its content.
+func (m *Memory) DumpFile(name string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := m.Dump(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns the kept entries, oldest first.
+func (m *Memory) Entries() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.full {
+		return append([][]byte(nil), m.entries[:m.next]...)
+	}
+	entries := make([][]byte, 0, len(m.entries))
+	entries = append(entries, m.entries[m.next:]...)
+	return append(entries, m.entries[:m.next]...)
+}
+
+// Close stops listening for the dump signal.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.signals != nil {
+		signal.Stop(m.signals)
+		close(m.done)
+		m.signals = nil
+	}
+	return nil
+}
+
+func (m *Memory) handleSignals(signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case <-signals:
+			_ = m.DumpFile(m.dumpFile)
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewMemory(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+size: 0
+encoder:
+ json:`, true},
+		{"case2", `
+size: 10
+encoder:
+ json:`, false},
+		{"case3", `
+dump_file: /tmp/dump.log
+dump_signal: SIGNOPE
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		_, err = New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+func TestMemory_Dump(t *testing.T) {
+	m := NewMemory(3)
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Dump(&buf))
+	assert.Empty(t, buf.String())
+
+	for _, s := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+		_, _ = m.Write([]byte(s))
+	}
+
+	assert.NoError(t, m.Dump(&buf))
+	assert.Equal(t, "3\n4\n5\n", buf.String())
+}
+
+func TestMemory_DumpOnFatal(t *testing.T) {
+	dumpFile := filepath.Join(t.TempDir(), "dump.log")
+
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"size":      2,
+		"dump_file": dumpFile,
+	})
+	w, err := New(cfg)
+	assert.NoError(t, err)
+	m := w.(*Memory)
+
+	assert.NoError(t, m.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel}, nil, []byte("info\n")))
+	assert.NoFileExists(t, dumpFile)
+
+	assert.NoError(t, m.WriteEntry(zapcore.Entry{Level: zapcore.FatalLevel}, nil, []byte("fatal\n")))
+	data, err := ioutil.ReadFile(dumpFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "info\nfatal\n", string(data))
+}
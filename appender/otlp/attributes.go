@@ -0,0 +1,74 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/khorevaa/logos/internal/common"
+)
+
+// attributeValue converts a value added to a zapcore.MapObjectEncoder to
+// an attribute value. Arrays and objects are sent as JSON strings.
+func attributeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, bool, int64, float64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case uint64:
+		return uintValue(v)
+	case uint32:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint:
+		return uintValue(uint64(v))
+	case uintptr:
+		return uintValue(uint64(v))
+	case float32:
+		return float64(v)
+	case []interface{}, map[string]interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return common.FormatValue(value)
+}
+
+// uintValue keeps the values above the int64 range exact as strings.
+func uintValue(v uint64) interface{} {
+	if v > math.MaxInt64 {
+		return strconv.FormatUint(v, 10)
+	}
+	return int64(v)
+}
+
+// decodeID decodes a hex encoded trace or span id, invalid ids are ignored.
+func decodeID(value interface{}, size int) []byte {
+	s, ok := value.(string)
+	if !ok || len(s) != size*2 {
+		return nil
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return id
+}
+
+func sortAttributes(attributes []attribute) {
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].key < attributes[j].key
+	})
+}
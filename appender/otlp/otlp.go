@@ -0,0 +1,394 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/httpclient"
+	"github.com/khorevaa/logos/internal/proto"
+	"go.uber.org/zap/zapcore"
+)
+
+const logsPath = "/v1/logs"
+
+type Config struct {
+	// Config.URL is the collector address, the /v1/logs path is appended if missing.
+	httpclient.Config `logos-config:",inline"`
+
+	// Format of the export request, protobuf or json.
+	Format string `logos-config:"format" logos-validate:"logos.oneof=protobuf json"`
+
+	// ServiceName is the service.name resource attribute, it defaults to
+	// the executable name.
+	ServiceName string            `logos-config:"service_name"`
+	Resource    map[string]string `logos-config:"resource"`
+
+	// Body is the source of the log record body: the entry message or the
+	// encoded entry.
+	Body string `logos-config:"body" logos-validate:"logos.oneof=message encoded"`
+
+	// TraceIDField and SpanIDField name the fields holding hex encoded
+	// trace context. They are not sent as attributes.
+	TraceIDField string `logos-config:"trace_id_field"`
+	SpanIDField  string `logos-config:"span_id_field"`
+
+	Batch batch.Config `logos-config:"batch"`
+}
+
+var (
+	defaultConfig = Config{
+		Config:       httpclient.DefaultConfig,
+		Format:       FormatProtobuf,
+		Body:         BodyMessage,
+		TraceIDField: "trace_id",
+		SpanIDField:  "span_id",
+		Batch: batch.Config{
+			Size:     512,
+			Bytes:    1 << 20,
+			Interval: time.Second,
+		},
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+const (
+	FormatProtobuf = "protobuf"
+	FormatJSON     = "json"
+
+	BodyMessage = "message"
+	BodyEncoded = "encoded"
+)
+
+// severityNumbers maps the levels to the OTLP SeverityNumber values.
+var severityNumbers = map[zapcore.Level]int64{
+	zapcore.DebugLevel:  5,
+	zapcore.InfoLevel:   9,
+	zapcore.WarnLevel:   13,
+	zapcore.ErrorLevel:  17,
+	zapcore.DPanicLevel: 19,
+	zapcore.PanicLevel:  21,
+	zapcore.FatalLevel:  23,
+}
+
+// Writer exports entries as OTLP log records over HTTP.
+type Writer struct {
+	client   *httpclient.Client
+	header   http.Header
+	format   string
+	resource []attribute
+	encoded  bool
+
+	traceIDField string
+	spanIDField  string
+
+	batcher *batch.Batcher
+}
+
+type attribute struct {
+	key string
+	// value is a string, bool, int64 or float64
+	value interface{}
+}
+
+type record struct {
+	scope        string
+	time         time.Time
+	observedTime time.Time
+	severity     int64
+	severityText string
+	body         string
+	attributes   []attribute
+	traceID      []byte
+	spanID       []byte
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if len(cfg.URL) > 0 && !strings.HasSuffix(cfg.URL, logsPath) {
+		cfg.URL = strings.TrimSuffix(cfg.URL, "/") + logsPath
+	}
+	client, err := httpclient.New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	switch cfg.Format {
+	case FormatJSON:
+		header.Set("Content-Type", "application/json")
+	default:
+		header.Set("Content-Type", "application/x-protobuf")
+	}
+
+	serviceName := cfg.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = filepath.Base(os.Args[0])
+	}
+	resource := []attribute{{"service.name", serviceName}}
+	for key, value := range cfg.Resource {
+		if key != "service.name" {
+			resource = append(resource, attribute{key, value})
+		}
+	}
+	sortAttributes(resource[1:])
+
+	w := &Writer{
+		client:       client,
+		header:       header,
+		format:       cfg.Format,
+		resource:     resource,
+		encoded:      cfg.Body == BodyEncoded,
+		traceIDField: cfg.TraceIDField,
+		spanIDField:  cfg.SpanIDField,
+	}
+	w.batcher = batch.New(cfg.Batch, w.send, nil)
+	return w, nil
+}
+
+// Write exports p as the body of an INFO record, for use without a logger.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	now := time.Now()
+	err = w.batcher.Add(&record{
+		time:         now,
+		observedTime: now,
+		severity:     severityNumbers[zapcore.InfoLevel],
+		severityText: zapcore.InfoLevel.CapitalString(),
+		body:         strings.TrimRight(string(p), "\r\n"),
+	}, len(p))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	r := &record{
+		scope:        ent.LoggerName,
+		time:         ent.Time,
+		observedTime: time.Now(),
+		severity:     severityNumbers[ent.Level],
+		severityText: ent.Level.CapitalString(),
+		body:         ent.Message,
+	}
+	if w.encoded {
+		r.body = strings.TrimRight(string(p), "\r\n")
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	for key, value := range enc.Fields {
+		switch key {
+		case w.traceIDField:
+			r.traceID = decodeID(value, 16)
+		case w.spanIDField:
+			r.spanID = decodeID(value, 8)
+		default:
+			r.attributes = append(r.attributes, attribute{key, attributeValue(value)})
+		}
+	}
+	if ent.Caller.Defined {
+		r.attributes = append(r.attributes,
+			attribute{"code.filepath", ent.Caller.File},
+			attribute{"code.lineno", int64(ent.Caller.Line)},
+		)
+	}
+	if len(ent.Stack) > 0 {
+		r.attributes = append(r.attributes, attribute{"exception.stacktrace", ent.Stack})
+	}
+	sortAttributes(r.attributes)
+
+	return w.batcher.Add(r, len(p))
+}
+
+// Sync exports the queued records.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	return w.batcher.Close()
+}
+
+func (w *Writer) send(items []interface{}) error {
+	var (
+		scopes []string
+		index  = map[string][]*record{}
+	)
+	for _, item := range items {
+		r := item.(*record)
+		if _, ok := index[r.scope]; !ok {
+			scopes = append(scopes, r.scope)
+		}
+		index[r.scope] = append(index[r.scope], r)
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	switch w.format {
+	case FormatJSON:
+		body, err = w.encodeJSON(scopes, index)
+	default:
+		body = w.encodeProtobuf(scopes, index)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.client.Post(body, w.header)
+	return err
+}
+
+// encodeProtobuf encodes the ExportLogsServiceRequest message.
+func (w *Writer) encodeProtobuf(scopes []string, records map[string][]*record) []byte {
+	var b proto.Buffer
+	b.Message(1, func(b *proto.Buffer) {
+		b.Message(1, func(b *proto.Buffer) {
+			for _, a := range w.resource {
+				encodeAttribute(b, 1, a)
+			}
+		})
+		for _, scope := range scopes {
+			b.Message(2, func(b *proto.Buffer) {
+				b.Message(1, func(b *proto.Buffer) {
+					b.String(1, scope)
+				})
+				for _, r := range records[scope] {
+					b.Message(2, func(b *proto.Buffer) {
+						encodeRecord(b, r)
+					})
+				}
+			})
+		}
+	})
+	return b.Bytes()
+}
+
+func encodeRecord(b *proto.Buffer, r *record) {
+	b.Fixed64(1, uint64(r.time.UnixNano()))
+	b.Int64(2, r.severity)
+	b.String(3, r.severityText)
+	b.Message(5, func(b *proto.Buffer) {
+		b.StringValue(1, r.body)
+	})
+	for _, a := range r.attributes {
+		encodeAttribute(b, 6, a)
+	}
+	b.BytesField(9, r.traceID)
+	b.BytesField(10, r.spanID)
+	b.Fixed64(11, uint64(r.observedTime.UnixNano()))
+}
+
+// encodeAttribute encodes a KeyValue message.
+func encodeAttribute(b *proto.Buffer, field int, a attribute) {
+	b.Message(field, func(b *proto.Buffer) {
+		b.String(1, a.key)
+		b.Message(2, func(b *proto.Buffer) {
+			switch v := a.value.(type) {
+			case bool:
+				b.BoolValue(2, v)
+			case int64:
+				b.Int64Value(3, v)
+			case float64:
+				b.DoubleValue(4, v)
+			default:
+				b.StringValue(1, v.(string))
+			}
+		})
+	})
+}
+
+type jsonKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// encodeJSON encodes the ExportLogsServiceRequest in the OTLP/JSON mapping.
+func (w *Writer) encodeJSON(scopes []string, records map[string][]*record) ([]byte, error) {
+	type jsonRecord struct {
+		TimeUnixNano         string                 `json:"timeUnixNano"`
+		ObservedTimeUnixNano string                 `json:"observedTimeUnixNano"`
+		SeverityNumber       int64                  `json:"severityNumber"`
+		SeverityText         string                 `json:"severityText"`
+		Body                 map[string]interface{} `json:"body"`
+		Attributes           []jsonKeyValue         `json:"attributes,omitempty"`
+		TraceID              string                 `json:"traceId,omitempty"`
+		SpanID               string                 `json:"spanId,omitempty"`
+	}
+	type jsonScopeLogs struct {
+		Scope      map[string]string `json:"scope"`
+		LogRecords []jsonRecord      `json:"logRecords"`
+	}
+
+	scopeLogs := make([]jsonScopeLogs, 0, len(scopes))
+	for _, scope := range scopes {
+		sl := jsonScopeLogs{Scope: map[string]string{}}
+		if len(scope) > 0 {
+			sl.Scope["name"] = scope
+		}
+		for _, r := range records[scope] {
+			sl.LogRecords = append(sl.LogRecords, jsonRecord{
+				TimeUnixNano:         strconv.FormatInt(r.time.UnixNano(), 10),
+				ObservedTimeUnixNano: strconv.FormatInt(r.observedTime.UnixNano(), 10),
+				SeverityNumber:       r.severity,
+				SeverityText:         r.severityText,
+				Body:                 map[string]interface{}{"stringValue": r.body},
+				Attributes:           jsonAttributes(r.attributes),
+				TraceID:              hex.EncodeToString(r.traceID),
+				SpanID:               hex.EncodeToString(r.spanID),
+			})
+		}
+		scopeLogs = append(scopeLogs, sl)
+	}
+
+	request := map[string]interface{}{
+		"resourceLogs": []interface{}{
+			map[string]interface{}{
+				"resource":  map[string]interface{}{"attributes": jsonAttributes(w.resource)},
+				"scopeLogs": scopeLogs,
+			},
+		},
+	}
+	return json.Marshal(request)
+}
+
+func jsonAttributes(attributes []attribute) []jsonKeyValue {
+	out := make([]jsonKeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		var value map[string]interface{}
+		switch v := a.value.(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int64:
+			// int64 values are strings in the JSON mapping
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": v}
+		}
+		out = append(out, jsonKeyValue{Key: a.key, Value: value})
+	}
+	return out
+}
@@ -0,0 +1,299 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewOTLP(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+url: http://127.0.0.1:4318
+service_name: demo
+encoder:
+ json:`, false},
+		{"case2", `
+service_name: demo
+encoder:
+ json:`, true},
+		{"case3", `
+url: http://127.0.0.1:4318
+format: grpc
+encoder:
+ json:`, true},
+		{"case4", `
+url: http://127.0.0.1:4318
+body: fields
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, _ := ioutil.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, data)
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) get() ([]*http.Request, [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests, r.bodies
+}
+
+func testConfig(url string) Config {
+	config := DefaultConfig()
+	config.URL = url
+	config.ServiceName = "demo"
+	config.Resource = map[string]string{"deployment.environment": "prod"}
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	return config
+}
+
+var entryTime = time.Unix(1600000000, 42)
+
+func writeEntry(t *testing.T, w *Writer) {
+	ent := zapcore.Entry{
+		LoggerName: "http",
+		Level:      zapcore.WarnLevel,
+		Time:       entryTime,
+		Message:    "slow request",
+	}
+	fields := []zapcore.Field{
+		zap.String("path", "/api"),
+		zap.Int("status", 200),
+		zap.Bool("cached", false),
+		zap.Float64("seconds", 1.5),
+		zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		zap.String("span_id", "00f067aa0ba902b7"),
+	}
+	assert.NoError(t, w.WriteEntry(ent, fields, []byte(`{"msg":"slow request"}`+"\n")))
+}
+
+func TestWriter_json(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Format = FormatJSON
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntry(t, w)
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := r.get()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, logsPath, requests[0].URL.Path)
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+
+	var request struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []jsonKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				Scope      map[string]string `json:"scope"`
+				LogRecords []struct {
+					TimeUnixNano   string                 `json:"timeUnixNano"`
+					SeverityNumber int                    `json:"severityNumber"`
+					SeverityText   string                 `json:"severityText"`
+					Body           map[string]interface{} `json:"body"`
+					Attributes     []jsonKeyValue         `json:"attributes"`
+					TraceID        string                 `json:"traceId"`
+					SpanID         string                 `json:"spanId"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	assert.NoError(t, json.Unmarshal(bodies[0], &request))
+
+	rl := request.ResourceLogs[0]
+	assert.Equal(t, []jsonKeyValue{
+		{"service.name", map[string]interface{}{"stringValue": "demo"}},
+		{"deployment.environment", map[string]interface{}{"stringValue": "prod"}},
+	}, rl.Resource.Attributes)
+
+	assert.Equal(t, "http", rl.ScopeLogs[0].Scope["name"])
+	rec := rl.ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "1600000000000000042", rec.TimeUnixNano)
+	assert.Equal(t, 13, rec.SeverityNumber)
+	assert.Equal(t, "WARN", rec.SeverityText)
+	assert.Equal(t, map[string]interface{}{"stringValue": "slow request"}, rec.Body)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rec.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", rec.SpanID)
+	assert.Equal(t, []jsonKeyValue{
+		{"cached", map[string]interface{}{"boolValue": false}},
+		{"path", map[string]interface{}{"stringValue": "/api"}},
+		{"seconds", map[string]interface{}{"doubleValue": 1.5}},
+		{"status", map[string]interface{}{"intValue": "200"}},
+	}, rec.Attributes)
+}
+
+// message is a decoded protobuf message: field number to raw values,
+// varints as uint64 and length delimited fields as []byte.
+type message map[int][]interface{}
+
+func decode(t *testing.T, b []byte) message {
+	m := message{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(b)
+			b = b[n:]
+			m[field] = append(m[field], v)
+		case 1:
+			m[field] = append(m[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			b = b[n:]
+			m[field] = append(m[field], b[:size])
+			b = b[size:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return m
+}
+
+func (m message) msg(t *testing.T, field int) message {
+	return decode(t, m[field][0].([]byte))
+}
+
+func (m message) str(field int) string {
+	return string(m[field][0].([]byte))
+}
+
+func TestWriter_protobuf(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	w, err := NewWriter(testConfig(srv.URL + "/v1/logs"))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntry(t, w)
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := r.get()
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "application/x-protobuf", requests[1].Header.Get("Content-Type"))
+
+	resourceLogs := decode(t, bodies[1]).msg(t, 1)
+	service := resourceLogs.msg(t, 1).msg(t, 1)
+	assert.Equal(t, "service.name", service.str(1))
+	assert.Equal(t, "demo", service.msg(t, 2).str(1))
+
+	scopeLogs := resourceLogs.msg(t, 2)
+	assert.Equal(t, "http", scopeLogs.msg(t, 1).str(1))
+
+	rec := scopeLogs.msg(t, 2)
+	assert.Equal(t, uint64(entryTime.UnixNano()), rec[1][0])
+	assert.Equal(t, uint64(13), rec[2][0])
+	assert.Equal(t, "WARN", rec.str(3))
+	assert.Equal(t, "slow request", rec.msg(t, 5).str(1))
+	assert.Len(t, rec[6], 4)
+	assert.Len(t, rec[9][0], 16)
+	assert.Len(t, rec[10][0], 8)
+
+	cached := decode(t, rec[6][0].([]byte))
+	assert.Equal(t, "cached", cached.str(1))
+	assert.Equal(t, []interface{}{uint64(0)}, cached.msg(t, 2)[2])
+}
+
+func TestWriter_encodedBody(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Body = BodyEncoded
+	config.TraceIDField = ""
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntry(t, w)
+	assert.NoError(t, w.Sync())
+
+	_, bodies := r.get()
+	rec := decode(t, bodies[0]).msg(t, 1).msg(t, 2).msg(t, 2)
+	assert.Equal(t, `{"msg":"slow request"}`, rec.msg(t, 5).str(1))
+	assert.Len(t, rec[6], 5)
+	assert.Nil(t, rec[9])
+}
+
+func TestSeverityNumbers(t *testing.T) {
+	for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+		assert.Contains(t, severityNumbers, l)
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{int32(-1), int64(-1)},
+		{uint32(7), int64(7)},
+		{uint64(math.MaxInt64), int64(math.MaxInt64)},
+		{uint64(math.MaxInt64) + 1, "9223372036854775808"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{uint(42), int64(42)},
+		{float32(0.5), float64(0.5)},
+		{[]interface{}{1, "a"}, `[1,"a"]`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, attributeValue(tt.value), "%T %v", tt.value, tt.value)
+	}
+}
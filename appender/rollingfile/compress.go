@@ -0,0 +1,122 @@
+package rollingfile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Compressor returns a writer compressing to w at the level, the level
+// is 0 if not configured.
+type Compressor func(w io.Writer, level int) (io.WriteCloser, error)
+
+type compression struct {
+	ext string
+	new Compressor
+}
+
+var (
+	compressionsMu sync.RWMutex
+	compressions   = map[string]compression{}
+)
+
+func init() {
+	RegisterCompressor("gzip", ".gz", func(w io.Writer, level int) (io.WriteCloser, error) {
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	})
+}
+
+// RegisterCompressor makes a compression_type available, the compressed
+// files get the extension ext. For example zstd with
+// github.com/klauspost/compress:
+//
+//	rollingfile.RegisterCompressor("zstd", ".zst", func(w io.Writer, level int) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+//	})
+func RegisterCompressor(name, ext string, c Compressor) {
+	compressionsMu.Lock()
+	defer compressionsMu.Unlock()
+
+	if _, exists := compressions[name]; exists {
+		panic(fmt.Errorf("compressor %q registered already", name))
+	}
+	compressions[name] = compression{ext, c}
+}
+
+func lookupCompression(name string, level int) (*compression, error) {
+	compressionsMu.RLock()
+	c, ok := compressions[name]
+	compressionsMu.RUnlock()
+
+	if !ok {
+		if name == "zstd" {
+			return nil, fmt.Errorf("compression_type zstd requires rollingfile.RegisterCompressor")
+		}
+		return nil, fmt.Errorf("unknown compression_type %q", name)
+	}
+	w, err := c.new(ioutil.Discard, level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compression_level %d: %s", level, err)
+	}
+	_ = w.Close()
+	return &c, nil
+}
+
+// compressedExts returns the extensions of all compressors.
+func compressedExts() []string {
+	compressionsMu.RLock()
+	defer compressionsMu.RUnlock()
+
+	var exts []string
+	for _, c := range compressions {
+		exts = append(exts, c.ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// compressFile replaces the file with its compressed copy, keeping the
+// modification time for the retention. An existing compressed file is
+// never replaced, the file is then kept.
+func (c *compression) compressFile(path string, level int, mode os.FileMode, modTime time.Time) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	target := path + c.ext
+	tmp := target + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	w, err := c.new(dst, level)
+	if err == nil {
+		_, err = io.Copy(w, src)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// unlike a rename, a link fails if the target exists
+		err = os.Link(tmp, target)
+	}
+	_ = os.Remove(tmp)
+	if err != nil {
+		return fmt.Errorf("can't compress log file: %s", err)
+	}
+	_ = os.Chtimes(target, modTime, modTime)
+	return os.Remove(path)
+}
@@ -0,0 +1,509 @@
+package rollingfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+)
+
+const megabyte = 1024 * 1024
+
+// Logger is a file writer rotating by size and on a schedule. The file
+// name may contain date verbs (%Y, %m, %d, %H, %M, %j), each period is
+// then written to its own file. Rotated files are removed after MaxAge,
+// beyond MaxBackups or MaxTotalSize and optionally compressed, in the
+// background.
+//
+// With Flock several processes can share the file: writes and rotations
+// are serialized through a lock file, a process finding the file rotated
+// by another one reopens it.
+type Logger struct {
+	name             string
+	fileMode         os.FileMode
+	createDirs       bool
+	dirMode          os.FileMode
+	maxSize          int64
+	maxAge           int
+	maxBackups       int
+	maxTotalSize     int64
+	compression      *compression
+	compressionLevel int
+	naming           backupNaming
+	currentLink      string
+	location         *time.Location
+	schedule         Schedule
+	files            *regexp.Regexp
+	lockName         string
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	lock     *os.File
+	file     *os.File
+	filename string
+	size     int64
+	next     time.Time
+	closed   bool
+
+	millMu      sync.Mutex
+	millPending bool
+	millRunning bool
+	millCurrent string
+	millWG      sync.WaitGroup
+}
+
+// NewLogger returns a logger for the config, the file is opened on the
+// first write.
+func NewLogger(cfg Config) (*Logger, error) {
+	if common.HasDateVerbs(filepath.Dir(cfg.FileName)) {
+		return nil, fmt.Errorf("date verbs are allowed only in the base name of file_name %q", cfg.FileName)
+	}
+	naming, err := newBackupNaming(cfg.BackupName, cfg.BackupTimeFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Logger{
+		name:             cfg.FileName,
+		fileMode:         cfg.FileMode.Perm(),
+		createDirs:       cfg.CreateDirs,
+		dirMode:          cfg.DirMode.Perm(),
+		maxSize:          int64(cfg.MaxSize) * megabyte,
+		maxAge:           cfg.MaxAge,
+		maxBackups:       cfg.MaxBackups,
+		maxTotalSize:     int64(cfg.MaxTotalSize) * megabyte,
+		compressionLevel: cfg.CompressionLevel,
+		naming:           naming,
+		currentLink:      cfg.CurrentLink,
+		location:         time.UTC,
+		files:            filesRegexp(cfg.FileName, naming, compressedExts()),
+		now:              time.Now,
+	}
+	if cfg.Flock {
+		l.lockName = cfg.LockFile
+		if l.lockName == "" {
+			l.lockName = cfg.FileName + ".lock"
+		}
+	}
+	if l.fileMode == 0 {
+		l.fileMode = defaultConfig.FileMode.Perm()
+	}
+	if l.dirMode == 0 {
+		l.dirMode = defaultConfig.DirMode.Perm()
+	}
+	if cfg.LocalTime {
+		l.location = time.Local
+	}
+	if cfg.RotateEvery != "" {
+		schedule, err := ParseSchedule(cfg.RotateEvery)
+		if err != nil {
+			return nil, err
+		}
+		l.schedule = schedule
+	}
+
+	compressionType := cfg.CompressionType
+	if compressionType == "" && cfg.Compress {
+		compressionType = "gzip"
+	}
+	if compressionType != "" && compressionType != "none" {
+		if l.compression, err = lookupCompression(compressionType, cfg.CompressionLevel); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *Logger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, os.ErrClosed
+	}
+	if l.lockName != "" {
+		if err := l.lockShared(); err != nil {
+			return 0, err
+		}
+		defer l.unlockShared()
+	}
+
+	now := l.now().In(l.location)
+	if err := l.openCurrent(now, len(p)); err != nil {
+		return 0, err
+	}
+	if !l.next.IsZero() && !now.Before(l.next) {
+		if err := l.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+func (l *Logger) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// Rotate renames the current file to a backup and opens a new one. It is
+// safe to call while other goroutines are writing.
+func (l *Logger) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return os.ErrClosed
+	}
+	if l.lockName != "" {
+		if err := l.lockShared(); err != nil {
+			return err
+		}
+		defer l.unlockShared()
+	}
+
+	now := l.now().In(l.location)
+	if err := l.openCurrent(now, 0); err != nil {
+		return err
+	}
+	return l.rotate(now)
+}
+
+// Close closes the file and waits for the background retention. The
+// later writes fail with os.ErrClosed.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	err := l.close()
+	if l.lock != nil {
+		_ = l.lock.Close()
+		l.lock = nil
+	}
+	l.mu.Unlock()
+
+	l.millWG.Wait()
+	return err
+}
+
+func (l *Logger) close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// openCurrent opens the file on the first write. Sharing the file, it
+// also follows a rotation made by another process and takes the size
+// written by all of them.
+func (l *Logger) openCurrent(now time.Time, writeLen int) error {
+	if l.file == nil {
+		return l.openExisting(now, writeLen)
+	}
+	if l.lockName == "" {
+		return nil
+	}
+
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if pathInfo, err := os.Stat(l.filename); err == nil && os.SameFile(info, pathInfo) {
+		l.size = info.Size()
+		return nil
+	}
+	_ = l.file.Close()
+	l.file = nil
+	return l.openExisting(now, writeLen)
+}
+
+// openExisting opens the file of the current period for appending. A file
+// left by a previous process is rotated first if it belongs to an
+// earlier period or has no room for the write.
+func (l *Logger) openExisting(now time.Time, writeLen int) error {
+	filename := common.ExpandDate(l.name, now)
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return l.openNew(filename, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	modTime := info.ModTime().In(l.location)
+	switch {
+	case l.schedule != nil && !now.Before(l.schedule.Next(modTime)):
+		if err := l.backup(filename, modTime); err != nil {
+			return err
+		}
+		return l.openNew(filename, now)
+	case l.maxSize > 0 && info.Size()+int64(writeLen) > l.maxSize:
+		if err := l.backup(filename, now); err != nil {
+			return err
+		}
+		return l.openNew(filename, now)
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, l.fileMode)
+	if err != nil {
+		return l.openNew(filename, now)
+	}
+	l.opened(file, filename, info.Size(), now)
+	return nil
+}
+
+// rotate closes the current file and opens the file of the period of now.
+// The current file is renamed to a backup unless the period has a
+// file name of its own.
+func (l *Logger) rotate(now time.Time) error {
+	if err := l.close(); err != nil {
+		return err
+	}
+	filename := common.ExpandDate(l.name, now)
+	if filename == l.filename {
+		if err := l.backup(filename, now); err != nil {
+			return err
+		}
+	}
+	return l.openNew(filename, now)
+}
+
+func (l *Logger) backup(filename string, t time.Time) error {
+	name := l.naming.name(filename, t, 0)
+	for seq := 1; backupExists(name); seq++ {
+		// rotations formatted to the same time must not replace a backup
+		name = l.naming.name(filename, t, seq)
+	}
+	if err := os.Rename(filename, name); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't rotate log file: %s", err)
+	}
+	return nil
+}
+
+// backupExists reports whether the backup name is taken, uncompressed,
+// waiting for the mill, or compressed.
+func backupExists(name string) bool {
+	if _, err := os.Lstat(name); !os.IsNotExist(err) {
+		return true
+	}
+	for _, ext := range compressedExts() {
+		if _, err := os.Lstat(name + ext); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Logger) openNew(filename string, now time.Time) error {
+	if l.createDirs {
+		if err := os.MkdirAll(filepath.Dir(filename), l.dirMode); err != nil {
+			return fmt.Errorf("can't make directories for log file: %s", err)
+		}
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, l.fileMode)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %s", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	l.opened(file, filename, info.Size(), now)
+	return nil
+}
+
+func (l *Logger) opened(file *os.File, filename string, size int64, now time.Time) {
+	l.file = file
+	l.filename = filename
+	l.size = size
+	if l.schedule != nil {
+		l.next = l.schedule.Next(now)
+	}
+	if l.currentLink != "" {
+		// the link is a convenience, logging goes on without it
+		_ = l.linkCurrent()
+	}
+	l.startMill()
+}
+
+// linkCurrent points the current link at the active file, replacing the
+// link atomically.
+func (l *Logger) linkCurrent() error {
+	target, err := filepath.Abs(l.filename)
+	if err != nil {
+		return err
+	}
+	linkDir, err := filepath.Abs(filepath.Dir(l.currentLink))
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(linkDir, target); err == nil && !strings.HasPrefix(rel, "..") {
+		target = rel
+	}
+
+	tmp := l.currentLink + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, l.currentLink); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// startMill applies the retention and compression in the background.
+// Requests made while the mill runs are merged into one more pass.
+func (l *Logger) startMill() {
+	if l.maxAge <= 0 && l.maxBackups <= 0 && l.maxTotalSize <= 0 && l.compression == nil {
+		return
+	}
+
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	l.millPending = true
+	l.millCurrent = l.filename
+	if l.millRunning {
+		return
+	}
+	l.millRunning = true
+	l.millWG.Add(1)
+	go l.runMill()
+}
+
+func (l *Logger) runMill() {
+	defer l.millWG.Done()
+	for {
+		l.millMu.Lock()
+		if !l.millPending {
+			l.millRunning = false
+			l.millMu.Unlock()
+			return
+		}
+		l.millPending = false
+		current := l.millCurrent
+		l.millMu.Unlock()
+
+		if l.lockName == "" {
+			_ = l.mill(current, l.now().In(l.location))
+			continue
+		}
+		// one of the processes sharing the file mills at a time, the
+		// others skip the pass
+		_ = l.withMillLock(func() error {
+			return l.mill(current, l.now().In(l.location))
+		})
+	}
+}
+
+type logFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	// time and seq are the rotation of the backup name, time is the
+	// modification time for the files of past periods.
+	time time.Time
+	seq  int
+}
+
+func (l *Logger) oldFiles(current string) ([]logFile, error) {
+	dir := filepath.Dir(l.name)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []logFile
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
+		if !info.Mode().IsRegular() || path == current || !l.files.MatchString(info.Name()) {
+			continue
+		}
+		t, seq, ok := l.naming.parseTime(l.files, info.Name(), l.location)
+		if !ok {
+			t = info.ModTime()
+		}
+		files = append(files, logFile{path, info.Size(), info.ModTime(), t, seq})
+	}
+	// newest first
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if !a.time.Equal(b.time) {
+			return a.time.After(b.time)
+		}
+		if a.seq != b.seq {
+			return a.seq > b.seq
+		}
+		return a.modTime.After(b.modTime)
+	})
+	return files, nil
+}
+
+func (l *Logger) mill(current string, now time.Time) error {
+	files, err := l.oldFiles(current)
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if l.maxAge > 0 {
+		cutoff = now.AddDate(0, 0, -l.maxAge)
+	}
+
+	var (
+		total    int64
+		firstErr error
+	)
+	for i, f := range files {
+		total += f.size
+		remove := l.maxBackups > 0 && i >= l.maxBackups ||
+			!cutoff.IsZero() && f.time.Before(cutoff) ||
+			l.maxTotalSize > 0 && total > l.maxTotalSize
+		switch {
+		case remove:
+			err = os.Remove(f.path)
+		case l.compression != nil && !l.compressed(f.path):
+			err = l.compression.compressFile(f.path, l.compressionLevel, l.fileMode, f.modTime)
+		default:
+			continue
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (l *Logger) compressed(path string) bool {
+	for _, ext := range compressedExts() {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package rollingfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/khorevaa/logos/internal/common"
+)
+
+// splitExt splits the base name of the file name from its extension.
+func splitExt(name string) (prefix, ext string) {
+	base := filepath.Base(name)
+	ext = filepath.Ext(base)
+	return base[:len(base)-len(ext)], ext
+}
+
+const (
+	// DefaultBackupName keeps the extension of the rotated files last.
+	DefaultBackupName = "{name}-{time}{ext}"
+	// DefaultBackupTimeFormat sorts and keeps rotations within a second apart.
+	DefaultBackupTimeFormat = "2006-01-02T15-04-05.000"
+)
+
+// backupNaming formats the names of the rotated files from a template with
+// the {name}, {ext} and {time} placeholders, e.g. "{name}{ext}.{time}".
+type backupNaming struct {
+	template string
+	layout   string
+}
+
+func newBackupNaming(template, layout string) (backupNaming, error) {
+	if template == "" {
+		template = DefaultBackupName
+	}
+	if layout == "" {
+		layout = DefaultBackupTimeFormat
+	}
+	b := backupNaming{template, layout}
+	if strings.Count(template, "{time}") != 1 {
+		return b, fmt.Errorf("backup_name %q must contain {time} once", template)
+	}
+	sample := b.name("app.log", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), 0)
+	if filepath.Dir(sample) != "." {
+		return b, fmt.Errorf("backup_name %q with backup_time_format %q must name a file of the same directory", template, layout)
+	}
+	return b, nil
+}
+
+// name returns the name of the file name rotated at t, a seq above zero
+// tells apart rotations formatted to the same time.
+func (b backupNaming) name(filename string, t time.Time, seq int) string {
+	prefix, ext := splitExt(filename)
+	ts := t.Format(b.layout)
+	if seq > 0 {
+		ts += "." + strconv.Itoa(seq)
+	}
+	base := strings.NewReplacer(
+		"{name}", prefix,
+		"{ext}", ext,
+		"{time}", ts,
+	).Replace(b.template)
+	return filepath.Join(filepath.Dir(filename), base)
+}
+
+// regexp returns the expression matching the backups of files matching
+// the prefix and ext expressions, with the time and seq groups.
+func (b backupNaming) regexp(prefix, ext string) string {
+	var sb strings.Builder
+	sb.WriteString(`(?P<time>`)
+	for _, c := range b.layout {
+		switch {
+		case c >= '0' && c <= '9':
+			sb.WriteString(`\d`)
+		case unicode.IsLetter(c):
+			sb.WriteString(`\pL`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString(`)(\.(?P<seq>\d+))?`)
+	timeRe := sb.String()
+
+	sb.Reset()
+	template := b.template
+	for len(template) > 0 {
+		switch {
+		case strings.HasPrefix(template, "{name}"):
+			sb.WriteString(prefix)
+			template = template[len("{name}"):]
+		case strings.HasPrefix(template, "{ext}"):
+			sb.WriteString(ext)
+			template = template[len("{ext}"):]
+		case strings.HasPrefix(template, "{time}"):
+			sb.WriteString(timeRe)
+			template = template[len("{time}"):]
+		default:
+			sb.WriteString(regexp.QuoteMeta(template[:1]))
+			template = template[1:]
+		}
+	}
+	return sb.String()
+}
+
+// parseTime returns the time and the sequence of a backup name matched
+// by re, ok is false if the name has no time.
+func (b backupNaming) parseTime(re *regexp.Regexp, base string, loc *time.Location) (t time.Time, seq int, ok bool) {
+	m := re.FindStringSubmatch(base)
+	if m == nil || m[re.SubexpIndex("time")] == "" {
+		return t, 0, false
+	}
+	t, err := time.ParseInLocation(b.layout, m[re.SubexpIndex("time")], loc)
+	if err != nil {
+		return t, 0, false
+	}
+	seq, _ = strconv.Atoi(m[re.SubexpIndex("seq")])
+	return t, seq, true
+}
+
+// filesRegexp matches the base names of the files written from the file
+// name pattern: the active files of every period, their backups and their
+// compressed copies.
+func filesRegexp(pattern string, naming backupNaming, compressedExts []string) *regexp.Regexp {
+	prefix, ext := splitExt(pattern)
+
+	var sb strings.Builder
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if c == '%' && i < len(prefix)-1 {
+			if re, ok := common.DateVerbs[prefix[i+1]]; ok {
+				sb.WriteString(re)
+				i++
+				continue
+			}
+			if prefix[i+1] == '%' {
+				sb.WriteString("%")
+				i++
+				continue
+			}
+		}
+		sb.WriteString(regexp.QuoteMeta(string(c)))
+	}
+	prefixRe, extRe := sb.String(), regexp.QuoteMeta(ext)
+
+	var compressed []string
+	for _, ext := range compressedExts {
+		compressed = append(compressed, regexp.QuoteMeta(ext))
+	}
+	return regexp.MustCompile("^(" + prefixRe + extRe + "|" + naming.regexp(prefixRe, extRe) + ")" +
+		"(" + strings.Join(compressed, "|") + ")?$")
+}
@@ -3,28 +3,43 @@ package rollingfile
 import (
 	"github.com/khorevaa/logos/internal/common"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type RollingFile struct {
-	zapcore.WriteSyncer
+	*Logger
 }
 
 type Config struct {
 	// FileName is the file to write logs to.  Backup log files will be retained
-	// in the same directory.  It uses <processname>-lumberjack.log in
-	// os.TempDir() if empty.
+	// in the same directory. The base name may contain the date verbs %Y, %m,
+	// %d, %H, %M and %j, e.g. app-%Y-%m-%d.log, every period is then written
+	// to its own file. Config variables like ${pid} or ${hostname} give
+	// every process its own file.
 	FileName string `logos-config:"file_name" logos-validate:"required"`
 
+	// FileMode is the permission of created files, 0644 by default.
+	FileMode common.FileMode `logos-config:"file_mode"`
+
+	// CreateDirs creates the missing directories of the file with DirMode,
+	// 0755 by default. It is on by default.
+	CreateDirs bool            `logos-config:"create_dirs"`
+	DirMode    common.FileMode `logos-config:"dir_mode"`
+
 	// MaxSize is the maximum size in megabytes of the log file before it gets
-	// rotated. It defaults to 100 megabytes.
-	MaxSize int `logos-config:"max_size" logos-validate:"min=1"`
+	// rotated. It defaults to 500 megabytes, 0 disables the size rotation.
+	MaxSize int `logos-config:"max_size" logos-validate:"min=0"`
+
+	// RotateEvery rotates the log file on a schedule: hourly, daily or a
+	// cron expression of five fields (minute hour day-of-month month
+	// day-of-week), e.g. "0 */6 * * *". It combines with MaxSize.
+	RotateEvery string `logos-config:"rotate_every"`
 
 	// MaxAge is the maximum number of days to retain old log files based on the
 	// timestamp encoded in their filename.  Note that a day is defined as 24
 	// hours and may not exactly correspond to calendar days due to daylight
-	// savings, leap seconds, etc. The default is not to remove old log files
-	// based on age.
+	// savings, leap seconds, etc. Files without a timestamp, like the dated
+	// files of past periods, are aged by their modification time. It
+	// defaults to 7 days.
 	MaxAge int `logos-config:"max_age"`
 
 	// MaxBackups is the maximum number of old log files to retain.  The default
@@ -32,33 +47,74 @@ type Config struct {
 	// deleted.)
 	MaxBackups int `logos-config:"max_backups"`
 
-	// LocalTime determines if the time used for formatting the timestamps in
-	// backup files is the computer's local time.  The default is to use UTC
-	// time.
+	// LocalTime determines if the schedule, the file name date verbs and the
+	// timestamps in backup files use the computer's local time.  The default
+	// is to use UTC time.
 	LocalTime bool `logos-config:"local_time"`
 
+	// MaxTotalSize is the maximum size in megabytes of all old log files,
+	// the oldest files are removed first. The default is no limit.
+	MaxTotalSize int `logos-config:"max_total_size" logos-validate:"min=0"`
+
 	// Compress determines if the rotated log files should be compressed
 	// using gzip. The default is not to perform compression.
 	Compress bool `logos-config:"compress"`
+
+	// CompressionType overrides Compress: none, gzip or a type added with
+	// RegisterCompressor, e.g. zstd. Files are compressed in the background.
+	CompressionType string `logos-config:"compression_type"`
+
+	// CompressionLevel is the level of the compressor, the compressor
+	// default if 0.
+	CompressionLevel int `logos-config:"compression_level"`
+
+	// BackupName is the name template of the rotated files with the
+	// {name}, {ext} and {time} placeholders. It defaults to
+	// "{name}-{time}{ext}", e.g. app-2006-01-02T15-04-05.000.log.
+	BackupName string `logos-config:"backup_name"`
+
+	// BackupTimeFormat is the Go time layout of {time}, it defaults to
+	// "2006-01-02T15-04-05.000".
+	BackupTimeFormat string `logos-config:"backup_time_format"`
+
+	// Flock lets several processes share the file: writes and rotations
+	// hold an advisory lock of LockFile, so entries never interleave and a
+	// single process renames and compresses while the others reopen.
+	Flock bool `logos-config:"flock"`
+
+	// LockFile is the lock file of Flock, FileName with the .lock
+	// extension by default.
+	LockFile string `logos-config:"lock_file"`
+
+	// CurrentLink is the path of a symbolic link kept pointing at the
+	// active file, e.g. /var/log/app/current. No link is made if empty.
+	CurrentLink string `logos-config:"current_link"`
 }
 
-func New(v *common.Config) (zapcore.WriteSyncer, error) {
-	cfg := Config{
-		MaxSize: 500,
+var (
+	defaultConfig = Config{
+		MaxSize:    500,
+		FileMode:   0644,
+		CreateDirs: true,
+		DirMode:    0755,
 	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
 	if err := v.Unpack(&cfg); err != nil {
 		return nil, err
 	}
 	if cfg.MaxAge == 0 {
 		cfg.MaxAge = 7
 	}
-	w := &lumberjack.Logger{
-		Filename:   cfg.FileName,
-		MaxSize:    cfg.MaxSize,
-		MaxAge:     cfg.MaxAge,
-		MaxBackups: cfg.MaxBackups,
-		LocalTime:  cfg.LocalTime,
-		Compress:   cfg.Compress,
+	l, err := NewLogger(cfg)
+	if err != nil {
+		return nil, err
 	}
-	return &RollingFile{zapcore.AddSync(w)}, nil
+	return &RollingFile{l}, nil
 }
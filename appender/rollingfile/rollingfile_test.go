@@ -1,9 +1,21 @@
 package rollingfile
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
 	"github.com/khorevaa/logos/internal/common"
 	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 func TestNewRollingFile(t *testing.T) {
@@ -21,6 +33,50 @@ encoder:
 file_name: /tmp/app.log
 encoder:
  json:`, false},
+		{"case3", `
+file_name: /tmp/app-%Y-%m-%d.log
+rotate_every: daily
+max_size: 0
+encoder:
+ json:`, false},
+		{"case4", `
+file_name: /tmp/app.log
+rotate_every: "*/5 * * * *"
+encoder:
+ json:`, false},
+		{"case5", `
+file_name: /tmp/app.log
+rotate_every: weekly
+encoder:
+ json:`, true},
+		{"case7", `
+file_name: /tmp/app.log
+compression_type: zstd
+encoder:
+ json:`, true},
+		{"case8", `
+file_name: /tmp/app.log
+compression_type: gzip
+compression_level: 42
+encoder:
+ json:`, true},
+		{"case9", `
+file_name: /tmp/app.log
+max_total_size: 1024
+backup_name: "{name}{ext}.{time}"
+backup_time_format: "20060102150405"
+current_link: /tmp/current
+encoder:
+ json:`, false},
+		{"case10", `
+file_name: /tmp/app.log
+backup_name: "{name}{ext}"
+encoder:
+ json:`, true},
+		{"case6", `
+file_name: /tmp/%Y/app.log
+encoder:
+ json:`, true},
 	}
 
 	for _, c := range tests {
@@ -30,3 +86,460 @@ encoder:
 		assert.Equal(t, c.hasErr, err != nil, c.name)
 	}
 }
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func testLogger(t *testing.T, cfg Config, c *clock) *Logger {
+	l, err := NewLogger(cfg)
+	assert.NoError(t, err)
+	l.now = c.now
+	return l
+}
+
+func files(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func read(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func write(t *testing.T, l *Logger, s string) {
+	_, err := l.Write([]byte(s))
+	assert.NoError(t, err)
+}
+
+func TestLogger_size(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := testLogger(t, Config{FileName: filepath.Join(dir, "app.log"), MaxSize: 1}, c)
+	l.maxSize = 11
+
+	write(t, l, "12345\n")
+	write(t, l, "1234\n")
+	c.add(time.Second)
+	write(t, l, "abc\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-05-01T10-00-01.000.log", "app.log"}, files(t, dir))
+	assert.Equal(t, "12345\n1234\n", read(t, filepath.Join(dir, "app-2024-05-01T10-00-01.000.log")))
+	assert.Equal(t, "abc\n", read(t, filepath.Join(dir, "app.log")))
+}
+
+func TestLogger_schedule(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)}
+	l := testLogger(t, Config{FileName: filepath.Join(dir, "app.log"), RotateEvery: "daily"}, c)
+
+	write(t, l, "day 1\n")
+	c.add(time.Minute)
+	write(t, l, "day 2\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-05-02T00-00-00.000.log", "app.log"}, files(t, dir))
+	assert.Equal(t, "day 1\n", read(t, filepath.Join(dir, "app-2024-05-02T00-00-00.000.log")))
+	assert.Equal(t, "day 2\n", read(t, filepath.Join(dir, "app.log")))
+}
+
+func TestLogger_pattern(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)}
+	cfg := Config{FileName: filepath.Join(dir, "app-%Y-%m-%d.log"), RotateEvery: "daily"}
+	l := testLogger(t, cfg, c)
+
+	write(t, l, "day 1\n")
+	c.add(time.Hour)
+	write(t, l, "day 2\n")
+	assert.NoError(t, l.Close())
+
+	// a restart later the same day appends to the file of the day
+	c.add(time.Hour)
+	l = testLogger(t, cfg, c)
+	write(t, l, "day 2 again\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-05-01.log", "app-2024-05-02.log"}, files(t, dir))
+	assert.Equal(t, "day 1\n", read(t, filepath.Join(dir, "app-2024-05-01.log")))
+	assert.Equal(t, "day 2\nday 2 again\n", read(t, filepath.Join(dir, "app-2024-05-02.log")))
+}
+
+func TestLogger_restart(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	assert.NoError(t, ioutil.WriteFile(name, []byte("yesterday\n"), 0644))
+	yesterday := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(name, yesterday, yesterday))
+
+	c := &clock{time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)}
+	cfg := Config{FileName: name, RotateEvery: "daily"}
+	l := testLogger(t, cfg, c)
+	write(t, l, "today\n")
+	assert.NoError(t, l.Close())
+
+	// the file of the current period is kept on the next restart
+	c.add(time.Hour)
+	l = testLogger(t, cfg, c)
+	write(t, l, "today again\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-05-01T18-00-00.000.log", "app.log"}, files(t, dir))
+	assert.Equal(t, "yesterday\n", read(t, filepath.Join(dir, "app-2024-05-01T18-00-00.000.log")))
+	assert.Equal(t, "today\ntoday again\n", read(t, name))
+}
+
+func TestLogger_retention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for day := 1; day <= 9; day++ {
+		name := filepath.Join(dir, common.ExpandDate("app-%Y-%m-%d.log", time.Date(2024, 5, day, 0, 0, 0, 0, time.UTC)))
+		assert.NoError(t, ioutil.WriteFile(name, []byte("old\n"), 0644))
+		modTime := time.Date(2024, 5, day, 23, 0, 0, 0, time.UTC)
+		assert.NoError(t, os.Chtimes(name, modTime, modTime))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.log"), nil, 0644))
+
+	c := &clock{now}
+	cfg := Config{
+		FileName:    filepath.Join(dir, "app-%Y-%m-%d.log"),
+		RotateEvery: "daily",
+		MaxAge:      5,
+		MaxBackups:  3,
+		Compress:    true,
+	}
+	l := testLogger(t, cfg, c)
+	write(t, l, "today\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{
+		"app-2024-05-07.log.gz",
+		"app-2024-05-08.log.gz",
+		"app-2024-05-09.log.gz",
+		"app-2024-05-10.log",
+		"other.log",
+	}, files(t, dir))
+
+	f, err := os.Open(filepath.Join(dir, "app-2024-05-09.log.gz"))
+	assert.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, "old\n", string(data))
+
+	info, err := f.Stat()
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(time.Date(2024, 5, 9, 23, 0, 0, 0, time.UTC)))
+}
+
+func TestLogger_maxAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	// the age comes from the name, not from the modification time
+	old := filepath.Join(dir, "app-2024-05-01T10-00-00.000.log")
+	recent := filepath.Join(dir, "app-2024-05-09T10-00-00.000.log")
+	for _, name := range []string{old, recent} {
+		assert.NoError(t, ioutil.WriteFile(name, []byte("old\n"), 0644))
+	}
+	assert.NoError(t, os.Chtimes(old, now, now))
+	modTime := now.AddDate(0, 0, -30)
+	assert.NoError(t, os.Chtimes(recent, modTime, modTime))
+
+	l := testLogger(t, Config{FileName: filepath.Join(dir, "app.log"), MaxAge: 5}, &clock{now})
+	write(t, l, "current\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-05-09T10-00-00.000.log", "app.log"}, files(t, dir))
+}
+
+func TestFilesRegexp(t *testing.T) {
+	naming, err := newBackupNaming("", "")
+	assert.NoError(t, err)
+	re := filesRegexp("/var/log/app-%Y-%m-%d.log", naming, []string{".gz", ".zst"})
+	assert.True(t, re.MatchString("app-2024-05-01.log"))
+	assert.True(t, re.MatchString("app-2024-05-01.log.gz"))
+	assert.True(t, re.MatchString("app-2024-05-01.log.zst"))
+	assert.True(t, re.MatchString("app-2024-05-01-2024-05-01T10-00-01.000.log"))
+	assert.True(t, re.MatchString("app-2024-05-01-2024-05-01T10-00-01.000.2.log.gz"))
+	assert.False(t, re.MatchString("app-2024-05-01.txt"))
+	assert.False(t, re.MatchString("app.log"))
+	assert.True(t, filesRegexp("/var/log/app-%%-%Y.log", naming, nil).MatchString("app-%-2024.log"))
+
+	naming, err = newBackupNaming("{name}{ext}.{time}", "20060102")
+	assert.NoError(t, err)
+	re = filesRegexp("/var/log/app.log", naming, []string{".gz"})
+	assert.True(t, re.MatchString("app.log"))
+	assert.True(t, re.MatchString("app.log.20240501"))
+	assert.True(t, re.MatchString("app.log.20240501.1.gz"))
+	assert.False(t, re.MatchString("app.log.old"))
+	assert.Equal(t, "/var/log/app.log.20240501", naming.name("/var/log/app.log", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), 0))
+
+	_, err = newBackupNaming("{name}{ext}", "")
+	assert.Error(t, err)
+	_, err = newBackupNaming("{time}/{name}{ext}", "")
+	assert.Error(t, err)
+}
+
+func TestLogger_totalSize(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 4; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app-2024-05-01T10-00-0%d.000.log", i))
+		assert.NoError(t, ioutil.WriteFile(name, make([]byte, megabyte/2), 0644))
+		modTime := time.Date(2024, 5, 1, 10, 0, i, 0, time.UTC)
+		assert.NoError(t, os.Chtimes(name, modTime, modTime))
+	}
+
+	c := &clock{time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)}
+	l := testLogger(t, Config{FileName: filepath.Join(dir, "app.log"), MaxTotalSize: 1}, c)
+	write(t, l, "current\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{
+		"app-2024-05-01T10-00-03.000.log",
+		"app-2024-05-01T10-00-04.000.log",
+		"app.log",
+	}, files(t, dir))
+}
+
+func TestLogger_naming(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	cfg := Config{
+		FileName:         filepath.Join(dir, "app.log"),
+		BackupName:       "{name}{ext}.{time}",
+		BackupTimeFormat: "20060102",
+		MaxBackups:       2,
+		CompressionType:  "gzip",
+		CompressionLevel: 9,
+		CurrentLink:      filepath.Join(dir, "current"),
+	}
+	l := testLogger(t, cfg, c)
+
+	for i := 0; i < 3; i++ {
+		write(t, l, "line\n")
+		assert.NoError(t, l.Rotate())
+		l.millWG.Wait()
+	}
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{
+		"app.log",
+		"app.log.20240501.1.gz",
+		"app.log.20240501.2.gz",
+		"current",
+	}, files(t, dir))
+
+	target, err := os.Readlink(filepath.Join(dir, "current"))
+	assert.NoError(t, err)
+	assert.Equal(t, "app.log", target)
+}
+
+func TestLogger_namingCollision(t *testing.T) {
+	dir := t.TempDir()
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write([]byte(s))
+		_ = gz.Close()
+		return buf.Bytes()
+	}
+	// a backup of the same period compressed by an earlier mill pass
+	first := filepath.Join(dir, "app.log.20240501.gz")
+	assert.NoError(t, ioutil.WriteFile(first, gzipped("first\n"), 0644))
+
+	c := &clock{time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := testLogger(t, Config{
+		FileName:         filepath.Join(dir, "app.log"),
+		BackupName:       "{name}{ext}.{time}",
+		BackupTimeFormat: "20060102",
+		Compress:         true,
+	}, c)
+	write(t, l, "second\n")
+	assert.NoError(t, l.Rotate())
+	l.millWG.Wait()
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app.log", "app.log.20240501.1.gz", "app.log.20240501.gz"}, files(t, dir))
+	assert.Equal(t, string(gzipped("first\n")), read(t, first))
+
+	// the compression never replaces a compressed file
+	name := filepath.Join(dir, "app.log.20240501")
+	assert.NoError(t, ioutil.WriteFile(name, []byte("third\n"), 0644))
+	assert.Error(t, l.compression.compressFile(name, 0, 0644, c.now()))
+	assert.Equal(t, "third\n", read(t, name))
+	assert.Equal(t, string(gzipped("first\n")), read(t, first))
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	registerIdentity.Do(func() {
+		RegisterCompressor("identity", ".id", func(w io.Writer, level int) (io.WriteCloser, error) {
+			return nopCloser{w}, nil
+		})
+	})
+	assert.Panics(t, func() {
+		RegisterCompressor("gzip", ".gz", nil)
+	})
+
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := testLogger(t, Config{FileName: filepath.Join(dir, "app.log"), CompressionType: "identity"}, c)
+	write(t, l, "line\n")
+	assert.NoError(t, l.Rotate())
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-05-01T10-00-00.000.log.id", "app.log"}, files(t, dir))
+	assert.Equal(t, "line\n", read(t, filepath.Join(dir, "app-2024-05-01T10-00-00.000.log.id")))
+}
+
+var registerIdentity sync.Once
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func TestLogger_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := testLogger(t, Config{FileName: filepath.Join(dir, "app.log")}, c)
+
+	write(t, l, "first\n")
+	assert.NoError(t, l.Rotate())
+	assert.NoError(t, l.Rotate())
+	write(t, l, "second\n")
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []string{
+		"app-2024-05-01T10-00-00.000.1.log",
+		"app-2024-05-01T10-00-00.000.log",
+		"app.log",
+	}, files(t, dir))
+	assert.Equal(t, "first\n", read(t, filepath.Join(dir, "app-2024-05-01T10-00-00.000.log")))
+	assert.Equal(t, "", read(t, filepath.Join(dir, "app-2024-05-01T10-00-00.000.1.log")))
+	assert.Equal(t, "second\n", read(t, filepath.Join(dir, "app.log")))
+}
+
+func TestLogger_RotateConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLogger(Config{FileName: filepath.Join(dir, "app.log")})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				write(t, l, "line\n")
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.Rotate())
+	}
+	wg.Wait()
+	assert.NoError(t, l.Close())
+
+	var total int
+	for _, name := range files(t, dir) {
+		total += strings.Count(read(t, filepath.Join(dir, name)), "line\n")
+	}
+	assert.Equal(t, 400, total)
+}
+
+func TestRollingFile_modes(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := common.NewConfigFrom(`
+file_name: ` + filepath.Join(dir, "logs", "${hostname}.log") + `
+file_mode: 0640
+dir_mode: 0750
+compress: true
+`)
+	assert.NoError(t, err)
+	w, err := New(cfg)
+	assert.NoError(t, err)
+	l := w.(*RollingFile)
+
+	_, err = l.Write([]byte("line\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Rotate())
+	assert.NoError(t, l.Close())
+
+	hostname, _ := os.Hostname()
+	for _, name := range files(t, filepath.Join(dir, "logs")) {
+		assert.True(t, strings.HasPrefix(name, hostname), name)
+		info, err := os.Stat(filepath.Join(dir, "logs", name))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm(), name)
+	}
+	info, err := os.Stat(filepath.Join(dir, "logs"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+}
+
+func TestLogger_flock(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{FileName: filepath.Join(dir, "app.log"), Flock: true, MaxBackups: 100}
+
+	// each logger stands for a process sharing the file
+	var loggers []*Logger
+	for i := 0; i < 3; i++ {
+		l, err := NewLogger(cfg)
+		assert.NoError(t, err)
+		l.maxSize = 100
+		loggers = append(loggers, l)
+	}
+
+	var wg sync.WaitGroup
+	for i, l := range loggers {
+		wg.Add(1)
+		go func(i int, l *Logger) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				write(t, l, fmt.Sprintf("logger %d line %02d\n", i, j))
+			}
+		}(i, l)
+	}
+	wg.Wait()
+	for _, l := range loggers {
+		assert.NoError(t, l.Close())
+	}
+
+	var lines int
+	for _, name := range files(t, dir) {
+		if strings.HasSuffix(name, ".lock") || strings.HasSuffix(name, ".mill") {
+			continue
+		}
+		content := read(t, filepath.Join(dir, name))
+		assert.True(t, len(content) <= 100, "%s has %d bytes", name, len(content))
+		for _, line := range strings.SplitAfter(content, "\n") {
+			if line != "" {
+				assert.Regexp(t, `^logger \d line \d\d\n$`, line)
+				lines++
+			}
+		}
+	}
+	assert.Equal(t, 150, lines)
+}
@@ -0,0 +1,164 @@
+package rollingfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the rotation times.
+type Schedule interface {
+	// Next returns the first rotation time after t.
+	Next(t time.Time) time.Time
+}
+
+var schedules = map[string]string{
+	"hourly": "0 * * * *",
+	"daily":  "0 0 * * *",
+}
+
+// ParseSchedule parses hourly, daily or a five field cron expression:
+// minute, hour, day of month, month and day of week.
+func ParseSchedule(spec string) (Schedule, error) {
+	if s, ok := schedules[spec]; ok {
+		spec = s
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid rotate_every %q: expected hourly, daily or 5 cron fields", spec)
+	}
+
+	var (
+		c   cron
+		err error
+	)
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		// 7 is Sunday too
+		c.dow |= 1
+	}
+	c.anyDom = fields[2] == "*"
+	c.anyDow = fields[4] == "*"
+	return &c, nil
+}
+
+// cron holds the allowed values of each field as bit sets.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid cron step in %q", field)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid cron value in %q", field)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid cron value in %q", field)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("cron value out of range in %q", field)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		// like cron, either field matches when both are restricted
+		return dom || dow
+	}
+}
+
+// Next steps through the wall clock of t's location. Days are advanced
+// with time.Date and hours in absolute time, so the DST transitions never
+// skip or repeat a rotation.
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	loc := t.Location()
+
+	// five years cover every valid expression, e.g. February 29th
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			next := t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			if c.skipped(t, next) {
+				// the matching hour does not exist on this day, rotate
+				// when the clock is set forward
+				return next
+			}
+			t = next
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// skipped reports whether a matching hour lies in the gap between the
+// consecutive hours from and to, when the clock is set forward.
+func (c *cron) skipped(from, to time.Time) bool {
+	if from.Day() != to.Day() {
+		return false
+	}
+	for h := from.Hour() + 1; h < to.Hour(); h++ {
+		if c.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package rollingfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec   string
+		hasErr bool
+	}{
+		{"hourly", false},
+		{"daily", false},
+		{"*/15 * * * *", false},
+		{"0 0,12 1-15 * 1-5", false},
+		{"0 0 * * 7", false},
+		{"weekly", true},
+		{"0 0 * *", true},
+		{"60 * * * *", true},
+		{"0 24 * * *", true},
+		{"0 0 0 * *", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+	}
+
+	for _, c := range tests {
+		_, err := ParseSchedule(c.spec)
+		assert.Equal(t, c.hasErr, err != nil, c.spec)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	date := func(loc *time.Location, month time.Month, day, hour, min int) time.Time {
+		return time.Date(2024, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"hourly", "hourly", date(time.UTC, 5, 1, 10, 0), date(time.UTC, 5, 1, 11, 0)},
+		{"daily", "daily", date(time.UTC, 5, 1, 10, 30), date(time.UTC, 5, 2, 0, 0)},
+		{"year end", "daily", date(time.UTC, 12, 31, 23, 59), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", date(time.UTC, 5, 1, 10, 16), date(time.UTC, 5, 1, 10, 30)},
+		{"monday", "0 0 * * 1", date(time.UTC, 5, 1, 10, 0), date(time.UTC, 5, 6, 0, 0)},
+		{"dom or dow", "0 0 10 * 1", date(time.UTC, 5, 7, 0, 0), date(time.UTC, 5, 10, 0, 0)},
+		{"leap day", "0 0 29 2 *", date(time.UTC, 3, 1, 0, 0), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+
+		{"daily spring forward", "daily", date(ny, 3, 9, 12, 0), date(ny, 3, 10, 0, 0)},
+		{"daily after spring forward", "daily", date(ny, 3, 10, 0, 0), date(ny, 3, 11, 0, 0)},
+		{"hourly spring forward", "hourly", date(ny, 3, 10, 1, 30), date(ny, 3, 10, 3, 0)},
+		{"skipped hour", "0 2 * * *", date(ny, 3, 9, 3, 0), date(ny, 3, 10, 3, 0)},
+		{"daily fall back", "daily", date(ny, 11, 2, 12, 0), date(ny, 11, 3, 0, 0)},
+		// 01:30 EDT is followed by 01:00 EST half an hour later
+		{"hourly fall back", "hourly", date(ny, 11, 3, 0, 30).Add(time.Hour), date(ny, 11, 3, 0, 30).Add(90 * time.Minute)},
+		{"repeated hour", "0 1 * * *", date(ny, 11, 3, 0, 30), date(ny, 11, 3, 1, 0)},
+	}
+
+	for _, c := range tests {
+		s, err := ParseSchedule(c.spec)
+		assert.NoError(t, err, c.name)
+		got := s.Next(c.from)
+		assert.True(t, c.want.Equal(got), "%s: want %s, got %s", c.name, c.want, got)
+	}
+}
@@ -0,0 +1,41 @@
+package rollingfile
+
+import (
+	"os"
+
+	"github.com/khorevaa/logos/internal/flock"
+)
+
+// lockShared takes the lock file shared by the processes writing the file.
+func (l *Logger) lockShared() error {
+	if l.lock == nil {
+		lock, err := os.OpenFile(l.lockName, os.O_CREATE|os.O_RDWR, l.fileMode)
+		if err != nil {
+			return err
+		}
+		l.lock = lock
+	}
+	return flock.Lock(l.lock)
+}
+
+func (l *Logger) unlockShared() {
+	if l.lock != nil {
+		_ = flock.Unlock(l.lock)
+	}
+}
+
+// withMillLock runs fn if no other process holds the mill lock.
+func (l *Logger) withMillLock(fn func() error) error {
+	lock, err := os.OpenFile(l.lockName+".mill", os.O_CREATE|os.O_RDWR, l.fileMode)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	ok, err := flock.TryLock(lock)
+	if err != nil || !ok {
+		return err
+	}
+	defer flock.Unlock(lock)
+	return fn()
+}
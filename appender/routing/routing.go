@@ -0,0 +1,493 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	RouteByField  = "field"
+	RouteByLogger = "logger"
+	RouteByLevel  = "level"
+)
+
+// ErrClosed is returned by the writes to a closed Router.
+var ErrClosed = errors.New("routing writer closed")
+
+// retryDelay is the time the error of a target is returned before the
+// target is created again.
+const retryDelay = 5 * time.Second
+
+// Factory creates the writer of a target from its config.
+type Factory func(config *common.Config) (zapcore.WriteSyncer, error)
+
+// Lookup returns the factory of a writer type, nil if the type is undefined.
+type Lookup func(writerType string) Factory
+
+type Config struct {
+	// RouteBy selects where the key of an entry comes from: the value of
+	// Field, the logger name or the level.
+	RouteBy string `logos-config:"route_by" logos-validate:"logos.oneof=field logger level"`
+	Field   string `logos-config:"field"`
+
+	// Default is the key of the entries without one. These entries are
+	// dropped if Default is empty.
+	Default string `logos-config:"default"`
+
+	// Target is the config of the writers with their writer type in `type`.
+	// The key fills the ${key} variable and the one named after Field,
+	// ${logger} or ${level}, e.g. file_name: /var/log/app/${tenant}.log.
+	// Targets are writers, not appenders: the entries are encoded and
+	// filtered by the encoder and level of the routing appender.
+	// A target failing to be created fails the writes of its key for 5
+	// seconds before it is tried again.
+	Target *common.Config `logos-config:"target" logos-validate:"required"`
+
+	// IdleTimeout closes the targets not written for that long, never if 0.
+	IdleTimeout time.Duration `logos-config:"idle_timeout" logos-validate:"min=0"`
+
+	// MaxOpen is the maximum number of open targets, the least recently
+	// written one is closed to open another.
+	MaxOpen int `logos-config:"max_open" logos-validate:"min=1"`
+}
+
+var (
+	defaultConfig = Config{
+		RouteBy:     RouteByField,
+		IdleTimeout: 5 * time.Minute,
+		MaxOpen:     100,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+// New creates a Router from the config, the target writers are created with
+// the factory returned by lookup for their type.
+func New(v *common.Config, lookup Lookup) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewRouter(cfg, lookup)
+}
+
+type targetConfig struct {
+	Type string `logos-config:"type" logos-validate:"required"`
+}
+
+// Router writes the entries to targets created on first use for each key.
+type Router struct {
+	routeBy     string
+	field       string
+	defaultKey  string
+	target      *common.Config
+	factory     Factory
+	idleTimeout time.Duration
+	maxOpen     int
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	routes map[string]*route
+	closed bool
+	done   chan struct{}
+}
+
+// route is the target of a key. It is added to the routes before the target
+// is created, the writes of the key wait for ready.
+type route struct {
+	// ready is closed once w or err is set.
+	ready chan struct{}
+	w     zapcore.WriteSyncer
+	err   error
+
+	mu     sync.Mutex
+	closed bool
+
+	// lastUsed and retryAt are guarded by the Router mutex.
+	lastUsed time.Time
+	retryAt  time.Time
+}
+
+// NewRouter creates a Router from the config.
+func NewRouter(cfg Config, lookup Lookup) (*Router, error) {
+	if cfg.RouteBy == RouteByField && len(cfg.Field) == 0 {
+		return nil, fmt.Errorf("routing by field needs a field name")
+	}
+	if cfg.Target == nil {
+		return nil, fmt.Errorf("routing needs a target")
+	}
+	var tc targetConfig
+	if err := cfg.Target.Unpack(&tc); err != nil {
+		return nil, err
+	}
+	if tc.Type == "routing" {
+		return nil, fmt.Errorf("routing target can not be routing")
+	}
+	factory := lookup(tc.Type)
+	if factory == nil {
+		return nil, fmt.Errorf("writer type %v undefined", tc.Type)
+	}
+
+	r := &Router{
+		routeBy:     cfg.RouteBy,
+		field:       cfg.Field,
+		defaultKey:  sanitizeKey(cfg.Default),
+		target:      cfg.Target,
+		factory:     factory,
+		idleTimeout: cfg.IdleTimeout,
+		maxOpen:     cfg.MaxOpen,
+		now:         time.Now,
+		routes:      map[string]*route{},
+	}
+	if r.maxOpen < 1 {
+		r.maxOpen = defaultConfig.MaxOpen
+	}
+	if r.idleTimeout > 0 {
+		r.done = make(chan struct{})
+		go r.closeIdleLoop(r.idleTimeout/2, r.done)
+	}
+	return r, nil
+}
+
+// key returns the target key of the entry, the default key if it has none.
+func (r *Router) key(ent zapcore.Entry, fields []zapcore.Field) string {
+	var key string
+	switch r.routeBy {
+	case RouteByLogger:
+		key = ent.LoggerName
+	case RouteByLevel:
+		key = ent.Level.String()
+	default:
+		// The last value wins like in the encoded entry.
+		for i := len(fields) - 1; i >= 0; i-- {
+			if fields[i].Key == r.field {
+				key = common.FieldValues(fields[i:i+1], r.field)[r.field]
+				break
+			}
+		}
+	}
+	if key = sanitizeKey(key); len(key) == 0 {
+		return r.defaultKey
+	}
+	return key
+}
+
+// sanitizeKey keeps the letters, digits, '.', '-' and '_' of the key, the
+// others are replaced with '_', and trims the leading dots so that a key
+// can not name another directory in a path.
+func sanitizeKey(key string) string {
+	key = strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '.' || c == '-' || c == '_' {
+			return c
+		}
+		return '_'
+	}, key)
+	return strings.TrimLeft(key, ".")
+}
+
+// keyVar is the target config variable named after the route.
+func (r *Router) keyVar() string {
+	if r.routeBy == RouteByField {
+		return r.field
+	}
+	return r.routeBy
+}
+
+func (r *Router) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	key := r.key(ent, fields)
+	if len(key) == 0 {
+		return nil
+	}
+	return r.write(key, func(w zapcore.WriteSyncer) error {
+		if ew, ok := w.(interface {
+			WriteEntry(zapcore.Entry, []zapcore.Field, []byte) error
+		}); ok {
+			return ew.WriteEntry(ent, fields, p)
+		}
+		_, err := w.Write(p)
+		return err
+	})
+}
+
+// Write writes p to the default target, entries are routed by WriteEntry.
+func (r *Router) Write(p []byte) (int, error) {
+	if len(r.defaultKey) == 0 {
+		return len(p), nil
+	}
+	err := r.write(r.defaultKey, func(w zapcore.WriteSyncer) error {
+		_, err := w.Write(p)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (r *Router) write(key string, write func(w zapcore.WriteSyncer) error) error {
+	for {
+		rt, err := r.route(key)
+		if err != nil {
+			return err
+		}
+		rt.mu.Lock()
+		if rt.closed {
+			// Closed as idle or evicted since route returned it.
+			rt.mu.Unlock()
+			continue
+		}
+		err = write(rt.w)
+		rt.mu.Unlock()
+		return err
+	}
+}
+
+// route returns the route of the key, creating its target if needed. The
+// target is created without holding the Router mutex, so a slow target
+// does not hold up the other keys.
+func (r *Router) route(key string) (*route, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrClosed
+	}
+	now := r.now()
+	rt, ok := r.routes[key]
+	if ok && rt.failed() && !now.Before(rt.retryAt) {
+		delete(r.routes, key)
+		ok = false
+	}
+	if ok {
+		rt.lastUsed = now
+		r.mu.Unlock()
+
+		<-rt.ready
+		if rt.err != nil {
+			return nil, rt.err
+		}
+		return rt, nil
+	}
+
+	rt = &route{ready: make(chan struct{}), lastUsed: now}
+	var evicted *route
+	if len(r.routes) >= r.maxOpen {
+		evicted = r.removeLeastRecentlyUsed()
+	}
+	r.routes[key] = rt
+	r.mu.Unlock()
+
+	if evicted != nil {
+		_ = evicted.close()
+	}
+
+	w, err := r.newTarget(key)
+
+	r.mu.Lock()
+	if err != nil {
+		rt.err = fmt.Errorf("routing target %q: %w", key, err)
+		rt.retryAt = r.now().Add(retryDelay)
+	} else {
+		rt.w = w
+	}
+	close(rt.ready)
+	r.mu.Unlock()
+
+	if rt.err != nil {
+		return nil, rt.err
+	}
+	return rt, nil
+}
+
+// created reports whether the target of the route is created.
+func (rt *route) created() bool {
+	select {
+	case <-rt.ready:
+		return rt.err == nil
+	default:
+		return false
+	}
+}
+
+// failed reports whether the target of the route failed to be created.
+func (rt *route) failed() bool {
+	select {
+	case <-rt.ready:
+		return rt.err != nil
+	default:
+		return false
+	}
+}
+
+func (r *Router) newTarget(key string) (zapcore.WriteSyncer, error) {
+	vars, err := common.NewConfigFrom(map[string]interface{}{
+		"key":      key,
+		r.keyVar(): key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := common.MergeConfigs(r.target, vars)
+	if err != nil {
+		return nil, err
+	}
+	return r.factory(cfg)
+}
+
+func (r *Router) removeLeastRecentlyUsed() *route {
+	var (
+		oldestKey string
+		oldest    *route
+	)
+	for key, rt := range r.routes {
+		// the targets being created are in use
+		if !rt.created() && !rt.failed() {
+			continue
+		}
+		if oldest == nil || rt.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = key, rt
+		}
+	}
+	if oldest != nil {
+		delete(r.routes, oldestKey)
+	}
+	return oldest
+}
+
+// Open returns the number of open targets.
+func (r *Router) Open() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	open := 0
+	for _, rt := range r.routes {
+		if rt.created() {
+			open++
+		}
+	}
+	return open
+}
+
+// closeIdle closes the targets not written since the idle timeout before now.
+func (r *Router) closeIdle(now time.Time) {
+	var idle []*route
+
+	r.mu.Lock()
+	for key, rt := range r.routes {
+		if (rt.created() || rt.failed()) && now.Sub(rt.lastUsed) >= r.idleTimeout {
+			idle = append(idle, rt)
+			delete(r.routes, key)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, rt := range idle {
+		_ = rt.close()
+	}
+}
+
+func (r *Router) closeIdleLoop(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.closeIdle(r.now())
+		case <-done:
+			return
+		}
+	}
+}
+
+// each calls fn with the writer of every open target and returns the first error.
+func (r *Router) each(fn func(w zapcore.WriteSyncer) error) error {
+	r.mu.Lock()
+	routes := make([]*route, 0, len(r.routes))
+	for _, rt := range r.routes {
+		if rt.created() {
+			routes = append(routes, rt)
+		}
+	}
+	r.mu.Unlock()
+
+	var first error
+	for _, rt := range routes {
+		rt.mu.Lock()
+		if !rt.closed {
+			if err := fn(rt.w); err != nil && first == nil {
+				first = err
+			}
+		}
+		rt.mu.Unlock()
+	}
+	return first
+}
+
+func (r *Router) Sync() error {
+	return r.each(func(w zapcore.WriteSyncer) error {
+		return w.Sync()
+	})
+}
+
+// Rotate rotates the open targets supporting it.
+func (r *Router) Rotate() error {
+	return r.each(func(w zapcore.WriteSyncer) error {
+		if rw, ok := w.(interface{ Rotate() error }); ok {
+			return rw.Rotate()
+		}
+		return nil
+	})
+}
+
+// Close closes all targets, the later writes fail with ErrClosed.
+func (r *Router) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	if r.done != nil {
+		close(r.done)
+	}
+	routes := r.routes
+	r.routes = nil
+	r.mu.Unlock()
+
+	var first error
+	for _, rt := range routes {
+		if err := rt.close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// close waits for the target creation and the write in progress and
+// closes the target.
+func (rt *route) close() error {
+	<-rt.ready
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if rt.closed || rt.w == nil {
+		return nil
+	}
+	rt.closed = true
+	if c, ok := rt.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return rt.w.Sync()
+}
@@ -0,0 +1,327 @@
+package routing
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/appender/file"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func lookup(writerType string) Factory {
+	if writerType == "file" {
+		return file.New
+	}
+	return nil
+}
+
+func newTestRouter(t *testing.T, config string) *Router {
+	cfg, err := common.NewConfigFrom(config)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	w, err := New(cfg, lookup)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	r := w.(*Router)
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	infos, err := ioutil.ReadDir(dir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	files := map[string]string{}
+	for _, info := range infos {
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		files[info.Name()] = string(data)
+	}
+	return files
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+field: tenant
+target:
+ type: file
+ file_name: /tmp/${tenant}.log`, false},
+		{"case2", `
+field: tenant`, true},
+		{"case3", `
+target:
+ type: file
+ file_name: /tmp/${tenant}.log`, true},
+		{"case4", `
+route_by: logger
+target:
+ type: file
+ file_name: /tmp/${logger}.log`, false},
+		{"case5", `
+route_by: thread
+target:
+ type: file
+ file_name: /tmp/${key}.log`, true},
+		{"case6", `
+field: tenant
+target:
+ type: kafka`, true},
+		{"case7", `
+field: tenant
+target:
+ file_name: /tmp/${tenant}.log`, true},
+		{"case8", `
+field: tenant
+max_open: 0
+target:
+ type: file
+ file_name: /tmp/${tenant}.log`, true},
+		{"case9", `
+field: tenant
+target:
+ type: routing`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg, lookup)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			assert.NoError(t, w.(*Router).Close(), c.name)
+		}
+	}
+}
+
+func TestRouter_field(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter(t, `
+field: tenant
+default: common
+target:
+ type: file
+ file_name: `+dir+`/${tenant}.log`)
+
+	write := func(msg string, fields ...zapcore.Field) {
+		assert.NoError(t, r.WriteEntry(zapcore.Entry{Message: msg}, fields, []byte(msg+"\n")))
+	}
+	write("1", zap.String("tenant", "acme"))
+	write("2", zap.String("tenant", "globex"), zap.Int("n", 2))
+	write("3", zap.String("tenant", "acme"))
+	write("4")
+	write("5", zap.Int("tenant", 42))
+	write("6", zap.String("tenant", "../../etc/passwd"))
+
+	assert.NoError(t, r.Sync())
+	assert.Equal(t, map[string]string{
+		"acme.log":           "1\n3\n",
+		"globex.log":         "2\n",
+		"common.log":         "4\n",
+		"42.log":             "5\n",
+		"_.._etc_passwd.log": "6\n",
+	}, readFiles(t, dir))
+}
+
+func TestRouter_noDefault(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter(t, `
+route_by: logger
+target:
+ type: file
+ file_name: `+dir+`/${logger}.log`)
+
+	assert.NoError(t, r.WriteEntry(zapcore.Entry{LoggerName: "app.db"}, nil, []byte("1\n")))
+	assert.NoError(t, r.WriteEntry(zapcore.Entry{}, nil, []byte("2\n")))
+	n, err := r.Write([]byte("3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, map[string]string{"app.db.log": "1\n"}, readFiles(t, dir))
+}
+
+func TestRouter_level(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter(t, `
+route_by: level
+target:
+ type: file
+ file_name: `+dir+`/app-${key}.log`)
+
+	for _, lvl := range []zapcore.Level{zapcore.InfoLevel, zapcore.ErrorLevel, zapcore.InfoLevel} {
+		assert.NoError(t, r.WriteEntry(zapcore.Entry{Level: lvl}, nil, []byte(lvl.String()+"\n")))
+	}
+
+	assert.Equal(t, map[string]string{
+		"app-info.log":  "info\ninfo\n",
+		"app-error.log": "error\n",
+	}, readFiles(t, dir))
+}
+
+func TestRouter_maxOpen(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter(t, `
+field: tenant
+max_open: 2
+target:
+ type: file
+ file_name: `+dir+`/${tenant}.log`)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	for _, tenant := range []string{"a", "b", "a", "c", "a", "b"} {
+		now = now.Add(time.Second)
+		assert.NoError(t, r.WriteEntry(zapcore.Entry{}, []zapcore.Field{zap.String("tenant", tenant)}, []byte(tenant+"\n")))
+		assert.LessOrEqual(t, r.Open(), 2)
+	}
+
+	r.mu.Lock()
+	var open []string
+	for key := range r.routes {
+		open = append(open, key)
+	}
+	r.mu.Unlock()
+	sort.Strings(open)
+	assert.Equal(t, []string{"a", "b"}, open)
+
+	assert.Equal(t, map[string]string{
+		"a.log": "a\na\na\n",
+		"b.log": "b\nb\n",
+		"c.log": "c\n",
+	}, readFiles(t, dir))
+}
+
+func TestRouter_idleTimeout(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter(t, `
+field: tenant
+idle_timeout: 1m
+target:
+ type: file
+ file_name: `+dir+`/${tenant}.log`)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	write := func(tenant string) {
+		assert.NoError(t, r.WriteEntry(zapcore.Entry{}, []zapcore.Field{zap.String("tenant", tenant)}, []byte(tenant+"\n")))
+	}
+	write("a")
+	now = now.Add(30 * time.Second)
+	write("b")
+
+	r.closeIdle(now.Add(40 * time.Second))
+	assert.Equal(t, 1, r.Open())
+	r.closeIdle(now.Add(time.Minute))
+	assert.Equal(t, 0, r.Open())
+
+	write("a")
+	assert.Equal(t, 1, r.Open())
+	assert.Equal(t, map[string]string{"a.log": "a\na\n", "b.log": "b\n"}, readFiles(t, dir))
+}
+
+func TestRouter_Close(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter(t, `
+field: tenant
+target:
+ type: file
+ file_name: `+dir+`/${tenant}.log`)
+
+	fields := []zapcore.Field{zap.String("tenant", "a")}
+	assert.NoError(t, r.WriteEntry(zapcore.Entry{}, fields, []byte("1\n")))
+	assert.NoError(t, r.Close())
+	assert.Equal(t, ErrClosed, r.WriteEntry(zapcore.Entry{}, fields, []byte("2\n")))
+	assert.NoError(t, r.Close())
+}
+
+func newFactoryRouter(t *testing.T, factory Factory) *Router {
+	r, err := NewRouter(Config{
+		RouteBy: RouteByLogger,
+		Target:  common.MustNewConfigFrom(`type: test`),
+		MaxOpen: 10,
+	}, func(string) Factory { return factory })
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func TestRouter_slowTarget(t *testing.T) {
+	release := make(chan struct{})
+	r := newFactoryRouter(t, func(config *common.Config) (zapcore.WriteSyncer, error) {
+		if key, _ := config.String("key", -1); key == "slow" {
+			<-release
+		}
+		return zapcore.AddSync(ioutil.Discard), nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.WriteEntry(zapcore.Entry{LoggerName: "slow"}, nil, []byte("1\n"))
+	}()
+	// the other keys are not held up by the slow target
+	assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.routes["slow"] != nil
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, r.WriteEntry(zapcore.Entry{LoggerName: "fast"}, nil, []byte("2\n")))
+	assert.Equal(t, 1, r.Open())
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, r.Open())
+}
+
+func TestRouter_failedTarget(t *testing.T) {
+	calls := 0
+	r := newFactoryRouter(t, func(config *common.Config) (zapcore.WriteSyncer, error) {
+		calls++
+		return nil, errors.New("can't open")
+	})
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	ent := zapcore.Entry{LoggerName: "broken"}
+	assert.EqualError(t, r.WriteEntry(ent, nil, []byte("1\n")), `routing target "broken": can't open`)
+	assert.Error(t, r.WriteEntry(ent, nil, []byte("2\n")))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, r.Open())
+
+	now = now.Add(retryDelay)
+	assert.Error(t, r.WriteEntry(ent, nil, []byte("3\n")))
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, r.Sync())
+}
+
+func TestSanitizeKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"acme":     "acme",
+		"a/b":      "a_b",
+		"..":       "",
+		".hidden":  "hidden",
+		"тенант-1": "тенант-1",
+		"a b:c":    "a_b_c",
+	} {
+		assert.Equal(t, want, sanitizeKey(key), key)
+	}
+}
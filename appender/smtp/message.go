@@ -0,0 +1,78 @@
+package smtp
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMessage formats the email with a quoted-printable plain text body,
+// and an html alternative if html is not empty.
+func buildMessage(from string, to []string, subject string, text, html []byte) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if len(html) == 0 {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQuotedPrintable(&b, text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	b.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var qp bytes.Buffer
+		if err := writeQuotedPrintable(&qp, part.content); err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(qp.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+func writeQuotedPrintable(b *bytes.Buffer, p []byte) error {
+	qp := quotedprintable.NewWriter(b)
+	if _, err := qp.Write(p); err != nil {
+		return err
+	}
+	return qp.Close()
+}
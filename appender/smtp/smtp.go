@@ -0,0 +1,346 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net"
+	"net/mail"
+	netsmtp "net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/notify"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	DefaultSubject = `[{{ .Level }}] {{ len .Entries }} log entries from {{ .Hostname }}`
+	DefaultBody    = `{{ .Text }}`
+)
+
+type Config struct {
+	Host string `logos-config:"host" logos-validate:"required"`
+	Port int    `logos-config:"port" logos-validate:"min=1"`
+
+	Username string `logos-config:"username"`
+	Password string `logos-config:"password"`
+
+	// Security is starttls to upgrade the connection, tls for implicit TLS
+	// or none. The tls section sets the certificates used by the first two,
+	// its enabled flag is ignored.
+	Security string           `logos-config:"security" logos-validate:"logos.oneof=starttls tls none"`
+	TLS      common.TLSConfig `logos-config:"tls"`
+	Timeout  time.Duration    `logos-config:"timeout"`
+
+	From string   `logos-config:"from" logos-validate:"required"`
+	To   []string `logos-config:"to" logos-validate:"required"`
+
+	// Subject, Body and HTML are templates. The email is multipart with
+	// an html part if HTML is set.
+	Subject string `logos-config:"subject" logos-validate:"required"`
+	Body    string `logos-config:"body" logos-validate:"required"`
+	HTML    string `logos-config:"html"`
+
+	// Window is the time entries are collected into one digest email of
+	// at most MaxEntries entries.
+	Window     time.Duration `logos-config:"window"`
+	MaxEntries int           `logos-config:"max_entries" logos-validate:"min=1"`
+	// MaxPerHour caps the emails sent per hour, 0 means no limit. The entries
+	// over the cap are counted in the next email.
+	MaxPerHour int `logos-config:"max_per_hour" logos-validate:"min=0"`
+}
+
+var (
+	defaultConfig = Config{
+		Port:       587,
+		Security:   SecurityStartTLS,
+		Timeout:    30 * time.Second,
+		Subject:    DefaultSubject,
+		Body:       DefaultBody,
+		Window:     time.Minute,
+		MaxEntries: 100,
+		MaxPerHour: 10,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+const (
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+	SecurityNone     = "none"
+)
+
+// Entry is a log entry in the template data.
+type Entry = notify.Entry
+
+// Data is passed to the templates.
+type Data struct {
+	Entries []Entry
+	// Level is the highest level of the entries.
+	Level    string
+	Hostname string
+	// Text is the encoded entries, one per line.
+	Text string
+	// Suppressed is the number of entries not sent because of the cap
+	// since the previous email.
+	Suppressed int
+}
+
+// Writer sends digest emails of the entries.
+type Writer struct {
+	address   string
+	host      string
+	security  string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	auth      netsmtp.Auth
+	hostname  string
+
+	// from and to are the header values, mailFrom and rcptTo the bare
+	// addresses of the envelope.
+	from     string
+	to       []string
+	mailFrom string
+	rcptTo   []string
+
+	subject *template.Template
+	body    *template.Template
+	html    *htmltemplate.Template
+
+	batcher *batch.Batcher
+
+	mu         sync.Mutex
+	limiter    notify.Limiter
+	suppressed int
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if len(cfg.Host) == 0 || len(cfg.From) == 0 || len(cfg.To) == 0 {
+		return nil, errors.New("smtp host, from and to are required")
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp from %q: %w", cfg.From, err)
+	}
+	rcptTo := make([]string, 0, len(cfg.To))
+	for _, to := range cfg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("smtp to %q: %w", to, err)
+		}
+		rcptTo = append(rcptTo, addr.Address)
+	}
+
+	w := &Writer{
+		address:  net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:     cfg.Host,
+		security: cfg.Security,
+		timeout:  cfg.Timeout,
+		from:     cfg.From,
+		to:       cfg.To,
+		mailFrom: from.Address,
+		rcptTo:   rcptTo,
+		limiter: notify.Limiter{
+			Limit:  cfg.MaxPerHour,
+			Window: time.Hour,
+		},
+	}
+
+	if w.security != SecurityNone {
+		tlsConfig := cfg.TLS
+		tlsConfig.Enabled = true
+		if w.tlsConfig, err = tlsConfig.Build(); err != nil {
+			return nil, err
+		}
+		if len(w.tlsConfig.ServerName) == 0 {
+			w.tlsConfig.ServerName = cfg.Host
+		}
+	}
+	if len(cfg.Username) > 0 {
+		w.auth = netsmtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	if w.hostname, err = os.Hostname(); err != nil {
+		return nil, err
+	}
+
+	if w.subject, err = template.New("subject").Funcs(notify.Funcs).Parse(cfg.Subject); err != nil {
+		return nil, fmt.Errorf("smtp subject template: %v", err)
+	}
+	if w.body, err = template.New("body").Funcs(notify.Funcs).Parse(cfg.Body); err != nil {
+		return nil, fmt.Errorf("smtp body template: %v", err)
+	}
+	if len(cfg.HTML) > 0 {
+		if w.html, err = htmltemplate.New("html").Funcs(notify.Funcs).Parse(cfg.HTML); err != nil {
+			return nil, fmt.Errorf("smtp html template: %v", err)
+		}
+	}
+
+	w.batcher = batch.New(batch.Config{
+		Size:     cfg.MaxEntries,
+		Interval: cfg.Window,
+	}, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.batcher.Add(notify.TextEntry(p), len(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	return w.batcher.Add(notify.NewEntry(ent, fields, p), len(p))
+}
+
+// Sync sends the collected entries.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	return w.batcher.Close()
+}
+
+func (w *Writer) send(items []interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.limiter.Allow() {
+		w.suppressed += len(items)
+		return nil
+	}
+
+	data := Data{
+		Entries:    make([]Entry, 0, len(items)),
+		Hostname:   w.hostname,
+		Suppressed: w.suppressed,
+	}
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		e := item.(*Entry)
+		data.Entries = append(data.Entries, *e)
+		lines = append(lines, e.Encoded)
+	}
+	data.Level = notify.MaxLevel(data.Entries).CapitalString()
+	data.Text = strings.Join(lines, "\n")
+	if data.Suppressed > 0 {
+		data.Text += fmt.Sprintf("\n(%d more entries suppressed)", data.Suppressed)
+	}
+
+	msg, err := w.message(data)
+	if err != nil {
+		return err
+	}
+	if err := w.deliver(msg); err != nil {
+		return err
+	}
+	w.suppressed = 0
+	return nil
+}
+
+func (w *Writer) message(data Data) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := w.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("smtp subject template: %v", err)
+	}
+	if err := w.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("smtp body template: %v", err)
+	}
+
+	var html []byte
+	if w.html != nil {
+		var b bytes.Buffer
+		if err := w.html.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("smtp html template: %v", err)
+		}
+		html = b.Bytes()
+	}
+
+	return buildMessage(w.from, w.to, subject.String(), body.Bytes(), html)
+}
+
+// deliver sends the message in one SMTP session.
+func (w *Writer) deliver(msg []byte) error {
+	dialer := &net.Dialer{Timeout: w.timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.security == SecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", w.address)
+	}
+	if err != nil {
+		return err
+	}
+	if w.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(w.timeout))
+	}
+
+	c, err := netsmtp.NewClient(conn, w.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Hello(w.hostname); err != nil {
+		return err
+	}
+	if w.security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(w.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if w.auth != nil {
+		if err := c.Auth(w.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(w.mailFrom); err != nil {
+		return err
+	}
+	for _, to := range w.rcptTo {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(msg); err != nil {
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,401 @@
+package smtp
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewSMTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+host: smtp.example.com
+from: logs@example.com
+to:
+  - ops@example.com
+encoder:
+ console:`, false},
+		{"case2", `
+host: smtp.example.com
+to:
+  - ops@example.com
+encoder:
+ console:`, true},
+		{"case3", `
+host: smtp.example.com
+from: logs@example.com
+to:
+  - ops@example.com
+security: ssl
+encoder:
+ console:`, true},
+		{"case4", `
+host: smtp.example.com
+from: logs@example.com
+to:
+  - ops@example.com
+subject: '{{ .Level'
+encoder:
+ console:`, true},
+		{"case5", `
+host: smtp.example.com
+from: Logs <logs@example.com
+to:
+  - ops@example.com
+encoder:
+ console:`, true},
+		{"case6", `
+host: smtp.example.com
+from: logs@example.com
+to:
+  - ops
+encoder:
+ console:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+type envelope struct {
+	from string
+	to   []string
+	auth string
+	tls  bool
+	data string
+}
+
+// server is an in-process SMTP stand-in accepting every message.
+type server struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	mails     chan envelope
+}
+
+func newServer(t *testing.T, tlsConfig *tls.Config) *server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &server{ln: ln, tlsConfig: tlsConfig, mails: make(chan envelope, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *server) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	var (
+		r   = bufio.NewReader(conn)
+		env envelope
+	)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch cmd {
+		case "EHLO":
+			if s.tlsConfig != nil && !env.tls {
+				reply("250-localhost")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-localhost")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, r, env.tls = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			auth, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			env.auth = string(auth)
+			reply("235 ok")
+		case "MAIL":
+			env.from = strings.TrimPrefix(line, "MAIL FROM:")
+			reply("250 ok")
+		case "RCPT":
+			env.to = append(env.to, strings.TrimPrefix(line, "RCPT TO:"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			env.data = data.String()
+			s.mails <- env
+			env = envelope{tls: env.tls}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *server) next(t *testing.T) envelope {
+	select {
+	case env := <-s.mails:
+		return env
+	case <-time.After(time.Second):
+		t.Fatal("no email received")
+		return envelope{}
+	}
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func testConfig(s *server) Config {
+	config := DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.Port = s.port()
+	config.Security = SecurityNone
+	config.From = "logs@example.com"
+	config.To = []string{"ops@example.com", "dev@example.com"}
+	config.Window = time.Hour
+	config.Timeout = time.Second
+	return config
+}
+
+func writeEntries(t *testing.T, w *Writer, messages ...string) {
+	for i, msg := range messages {
+		level := zapcore.WarnLevel
+		if i > 0 {
+			level = zapcore.ErrorLevel
+		}
+		ent := zapcore.Entry{Level: level, Message: msg}
+		assert.NoError(t, w.WriteEntry(ent, nil, []byte(level.CapitalString()+" "+msg+"\n")))
+	}
+}
+
+func readMail(t *testing.T, env envelope) *mail.Message {
+	msg, err := mail.ReadMessage(strings.NewReader(env.data))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return msg
+}
+
+func TestWriter_digest(t *testing.T) {
+	s := newServer(t, nil)
+
+	w, err := NewWriter(testConfig(s))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntries(t, w, "disk almost full", "disk full")
+	assert.NoError(t, w.Sync())
+
+	env := s.next(t)
+	assert.Equal(t, "<logs@example.com>", env.from)
+	assert.Equal(t, []string{"<ops@example.com>", "<dev@example.com>"}, env.to)
+
+	msg := readMail(t, env)
+	assert.Equal(t, "[ERROR] 2 log entries from "+w.hostname, msg.Header.Get("Subject"))
+	assert.Equal(t, "ops@example.com, dev@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(quotedprintable.NewReader(msg.Body))
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN disk almost full\r\nERROR disk full\r\n", string(body))
+}
+
+func TestWriter_displayNames(t *testing.T) {
+	s := newServer(t, nil)
+
+	config := testConfig(s)
+	config.From = "App Logs <logs@example.com>"
+	config.To = []string{`"Ops Team" <ops@example.com>`, "dev@example.com"}
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntries(t, w, "disk full")
+	assert.NoError(t, w.Sync())
+
+	env := s.next(t)
+	assert.Equal(t, "<logs@example.com>", env.from)
+	assert.Equal(t, []string{"<ops@example.com>", "<dev@example.com>"}, env.to)
+
+	msg := readMail(t, env)
+	from, err := msg.Header.AddressList("From")
+	assert.NoError(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "App Logs", Address: "logs@example.com"}}, from)
+}
+
+func TestWriter_html(t *testing.T) {
+	s := newServer(t, nil)
+
+	config := testConfig(s)
+	config.Subject = `Ünicode {{ len .Entries }}`
+	config.HTML = `<ul>{{ range .Entries }}<li>{{ .Message }}</li>{{ end }}</ul>`
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntries(t, w, "<script>")
+	assert.NoError(t, w.Sync())
+
+	msg := readMail(t, s.next(t))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Ünicode 1", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, _ := ioutil.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(b))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=utf-8: WARN <script>",
+		"text/html; charset=utf-8: <ul><li>&lt;script&gt;</li></ul>",
+	}, parts)
+}
+
+func TestWriter_startTLS(t *testing.T) {
+	s := newServer(t, &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t)}})
+
+	config := testConfig(s)
+	config.Security = SecurityStartTLS
+	config.TLS.InsecureSkipVerify = true
+	config.Username = "user"
+	config.Password = "secret"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntries(t, w, "alert")
+	assert.NoError(t, w.Sync())
+
+	env := s.next(t)
+	assert.True(t, env.tls)
+	assert.Equal(t, "\x00user\x00secret", env.auth)
+}
+
+func TestWriter_startTLSRequired(t *testing.T) {
+	s := newServer(t, nil)
+
+	config := testConfig(s)
+	config.Security = SecurityStartTLS
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeEntries(t, w, "alert")
+	assert.Error(t, w.Sync())
+}
+
+func TestWriter_maxPerHour(t *testing.T) {
+	s := newServer(t, nil)
+
+	config := testConfig(s)
+	config.MaxPerHour = 1
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+	w.limiter.Window = 50 * time.Millisecond
+
+	writeEntries(t, w, "first")
+	assert.NoError(t, w.Sync())
+	writeEntries(t, w, "second", "third")
+	assert.NoError(t, w.Sync())
+	s.next(t)
+
+	time.Sleep(60 * time.Millisecond)
+	writeEntries(t, w, "fourth")
+	assert.NoError(t, w.Sync())
+
+	body, err := ioutil.ReadAll(quotedprintable.NewReader(readMail(t, s.next(t)).Body))
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN fourth\r\n(2 more entries suppressed)\r\n", string(body))
+	assert.Len(t, s.mails, 0)
+}
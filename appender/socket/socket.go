@@ -0,0 +1,245 @@
+package socket
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	Network string           `logos-config:"network" logos-validate:"logos.oneof=tcp udp unix unixgram"`
+	Address string           `logos-config:"address" logos-validate:"required"`
+	TLS     common.TLSConfig `logos-config:"tls"`
+
+	// Timeout applies to dialing and to each write.
+	Timeout time.Duration `logos-config:"timeout"`
+
+	// BackoffMin and BackoffMax bound the delay between reconnect attempts.
+	BackoffMin time.Duration `logos-config:"backoff_min"`
+	BackoffMax time.Duration `logos-config:"backoff_max"`
+
+	// BufferSize is the number of entries kept while disconnected.
+	// The oldest entries are dropped when it is full.
+	BufferSize int `logos-config:"buffer_size" logos-validate:"min=0"`
+}
+
+var (
+	defaultConfig = Config{
+		Network:    "tcp",
+		Timeout:    5 * time.Second,
+		BackoffMin: 100 * time.Millisecond,
+		BackoffMax: 30 * time.Second,
+		BufferSize: 1000,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+// State is the connection state of the socket writer.
+type State int32
+
+const (
+	Disconnected State = iota
+	Connected
+)
+
+func (s State) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
+
+var (
+	ErrNotConnected = errors.New("socket is not connected")
+	ErrClosed       = errors.New("socket writer closed")
+)
+
+// Writer writes encoded entries to a socket, reconnecting with backoff
+// and buffering entries while disconnected.
+type Writer struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+
+	mu        sync.Mutex
+	conn      net.Conn
+	state     State
+	reconnect common.Reconnect
+	closed    bool
+
+	buffer *common.MessageQueue
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && cfg.Network != "tcp" && cfg.Network != "unix" {
+		return nil, fmt.Errorf("tls is not supported for network %q", cfg.Network)
+	}
+
+	return &Writer{
+		network:   cfg.Network,
+		address:   cfg.Address,
+		tlsConfig: tlsConfig,
+		timeout:   cfg.Timeout,
+		reconnect: common.Reconnect{
+			Name: fmt.Sprintf("socket %s://%s", cfg.Network, cfg.Address),
+			Backoff: common.Backoff{
+				Min: cfg.BackoffMin,
+				Max: cfg.BackoffMax,
+			},
+		},
+		buffer: common.NewMessageQueue(cfg.BufferSize),
+	}, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	msg := make([]byte, len(p))
+	copy(msg, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	err = w.flush()
+	if err == nil {
+		err = w.send(msg)
+	}
+	if err != nil && !w.buffer.Push(msg) {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync sends the buffered entries. It fails while disconnected.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	return w.flush()
+}
+
+// Close closes the connection, the later writes fail with ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	w.state = Disconnected
+	return err
+}
+
+// State returns the current connection state.
+func (w *Writer) State() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+// Buffered returns the number of entries waiting for the connection.
+func (w *Writer) Buffered() int {
+	return w.buffer.Len()
+}
+
+// Dropped returns the number of entries dropped because the buffer was full.
+func (w *Writer) Dropped() int {
+	return w.buffer.Dropped()
+}
+
+// flush sends the buffered entries in order, w.mu must be held.
+func (w *Writer) flush() error {
+	for {
+		msg, ok := w.buffer.Pop()
+		if !ok {
+			return nil
+		}
+		if err := w.send(msg); err != nil {
+			w.buffer.PushFront(msg)
+			return err
+		}
+	}
+}
+
+// send writes the message, connecting first if needed, w.mu must be held.
+func (w *Writer) send(msg []byte) error {
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	if w.timeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.disconnected(err)
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) connect() error {
+	if w.reconnect.Waiting() {
+		return ErrNotConnected
+	}
+
+	dialer := &net.Dialer{Timeout: w.timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, w.network, w.address, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err != nil {
+		w.disconnected(err)
+		return err
+	}
+
+	w.conn = conn
+	w.state = Connected
+	w.reconnect.Connected()
+	return nil
+}
+
+func (w *Writer) disconnected(err error) {
+	w.state = Disconnected
+	w.reconnect.Failed(err)
+}
@@ -0,0 +1,167 @@
+package socket
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSocket(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+network: tcp
+address: 127.0.0.1:5170
+encoder:
+ json:`, false},
+		{"case2", `
+network: tcp
+encoder:
+ json:`, true},
+		{"case3", `
+network: sctp
+address: 127.0.0.1:5170
+encoder:
+ json:`, true},
+		{"case4", `
+network: udp
+address: 127.0.0.1:5170
+tls:
+  enabled: true
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		_, err = New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+func readLines(t *testing.T, ln net.Listener, n int) <-chan []string {
+	result := make(chan []string, 1)
+	go func() {
+		defer close(result)
+		conn, err := ln.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		r := bufio.NewReader(conn)
+		var lines []string
+		for i := 0; i < n; i++ {
+			line, err := r.ReadString('\n')
+			assert.NoError(t, err)
+			lines = append(lines, line)
+		}
+		result <- lines
+	}()
+	return result
+}
+
+func testConfig(network, address string) Config {
+	cfg := DefaultConfig()
+	cfg.Network = network
+	cfg.Address = address
+	cfg.BackoffMin = time.Millisecond
+	cfg.BackoffMax = time.Millisecond
+	return cfg
+}
+
+func TestWriter_reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+
+	w, err := NewWriter(testConfig("tcp", addr))
+	assert.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, Disconnected, w.State())
+
+	lines := readLines(t, ln, 1)
+	_, err = w.Write([]byte("{\"msg\":\"1\"}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Connected, w.State())
+	assert.Equal(t, []string{"{\"msg\":\"1\"}\n"}, <-lines)
+	_ = ln.Close()
+
+	// the peer is gone, writes fail until the connection is noticed as broken
+	for i := 0; i < 100 && w.State() == Connected; i++ {
+		_, err = w.Write([]byte("{\"msg\":\"lost\"}\n"))
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, Disconnected, w.State())
+	assert.True(t, w.Buffered() > 0)
+
+	ln, err = net.Listen("tcp", addr)
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	lines = readLines(t, ln, w.Buffered())
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, Connected, w.State())
+	assert.Equal(t, 0, w.Buffered())
+	assert.NotEmpty(t, <-lines)
+}
+
+func TestWriter_bufferLimit(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	cfg := testConfig("tcp", addr)
+	cfg.BufferSize = 2
+	w, err := NewWriter(cfg)
+	assert.NoError(t, err)
+
+	for _, msg := range []string{"1\n", "2\n", "3\n"} {
+		_, err = w.Write([]byte(msg))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, w.Buffered())
+	assert.Equal(t, 1, w.Dropped())
+
+	ln, err = net.Listen("tcp", addr)
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	lines := readLines(t, ln, 2)
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, []string{"2\n", "3\n"}, <-lines)
+}
+
+func TestWriter_unixgram(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "agent.sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	w, err := NewWriter(testConfig("unixgram", addr))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("{\"msg\":\"datagram\"}\n"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"msg\":\"datagram\"}\n", string(buf[:n]))
+}
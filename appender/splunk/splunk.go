@@ -0,0 +1,436 @@
+package splunk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/httpclient"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	collectorPath = "/services/collector"
+	eventPath     = collectorPath + "/event"
+	ackPath       = collectorPath + "/ack"
+)
+
+type Config struct {
+	// Config.URL is the HEC address, the event endpoint path is appended
+	// if the URL has no /services/collector path.
+	httpclient.Config `logos-config:",inline"`
+
+	Token string `logos-config:"token" logos-validate:"required"`
+
+	// Host defaults to the host name.
+	Host       string            `logos-config:"host"`
+	Source     string            `logos-config:"source"`
+	SourceType string            `logos-config:"sourcetype"`
+	Index      string            `logos-config:"index"`
+	Fields     map[string]string `logos-config:"fields"`
+	// FieldNames are the entry fields copied into the indexed fields.
+	FieldNames []string `logos-config:"field_names"`
+
+	// Ack checks the indexer acknowledgment of every request, polled every
+	// AckInterval in background. A request not acknowledged within
+	// AckTimeout is sent again, up to MaxRetries times. Sync waits for the
+	// pending acknowledgments. Channel is generated if empty.
+	Ack         bool          `logos-config:"ack"`
+	Channel     string        `logos-config:"channel"`
+	AckTimeout  time.Duration `logos-config:"ack_timeout"`
+	AckInterval time.Duration `logos-config:"ack_interval"`
+
+	Batch batch.Config `logos-config:"batch"`
+}
+
+var (
+	defaultConfig = Config{
+		Config:      httpclient.DefaultConfig,
+		AckTimeout:  30 * time.Second,
+		AckInterval: time.Second,
+		Batch: batch.Config{
+			Size:     100,
+			Bytes:    1 << 20,
+			Interval: time.Second,
+		},
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+var ErrAckTimeout = errors.New("splunk hec: acknowledgment timed out")
+
+// Writer sends entries to a Splunk HTTP Event Collector.
+type Writer struct {
+	client *httpclient.Client
+	header http.Header
+
+	host       string
+	source     string
+	sourceType string
+	index      string
+	fields     map[string]string
+	fieldNames []string
+
+	ack         bool
+	ackURL      string
+	ackTimeout  time.Duration
+	ackInterval time.Duration
+	maxResends  int
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	ackMu   sync.Mutex
+	acked   *sync.Cond
+	pending map[int64]*pendingAck
+	// resending counts the requests being sent again.
+	resending int
+	ackErr    error
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	batcher *batch.Batcher
+}
+
+// pendingAck is a request waiting for its indexer acknowledgment.
+type pendingAck struct {
+	body     []byte
+	deadline time.Time
+	resends  int
+}
+
+// event is the HEC event envelope.
+type event struct {
+	Time       json.Number       `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      json.RawMessage   `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if len(cfg.Token) == 0 {
+		return nil, errors.New("splunk hec token is required")
+	}
+
+	base := cfg.URL
+	if i := strings.Index(cfg.URL, collectorPath); i >= 0 {
+		base = cfg.URL[:i]
+	} else if len(cfg.URL) > 0 {
+		base = strings.TrimSuffix(cfg.URL, "/")
+		cfg.URL = base + eventPath
+	}
+	client, err := httpclient.New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", "Splunk "+cfg.Token)
+
+	w := &Writer{
+		client:      client,
+		header:      header,
+		host:        cfg.Host,
+		source:      cfg.Source,
+		sourceType:  cfg.SourceType,
+		index:       cfg.Index,
+		fields:      cfg.Fields,
+		fieldNames:  cfg.FieldNames,
+		ack:         cfg.Ack,
+		ackTimeout:  cfg.AckTimeout,
+		ackInterval: cfg.AckInterval,
+		maxResends:  cfg.MaxRetries,
+		now:         time.Now,
+	}
+	if len(w.host) == 0 {
+		if w.host, err = os.Hostname(); err != nil {
+			return nil, err
+		}
+	}
+	if w.ack {
+		channel := cfg.Channel
+		if len(channel) == 0 {
+			if channel, err = newChannel(); err != nil {
+				return nil, err
+			}
+		}
+		header.Set("X-Splunk-Request-Channel", channel)
+		w.ackURL = base + ackPath + "?channel=" + url.QueryEscape(channel)
+
+		w.acked = sync.NewCond(&w.ackMu)
+		w.pending = map[int64]*pendingAck{}
+		w.done = make(chan struct{})
+		w.wg.Add(1)
+		go w.pollAcks()
+	}
+
+	w.batcher = batch.New(cfg.Batch, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.add(time.Now(), nil, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	return w.add(ent.Time, fields, p)
+}
+
+// Sync sends the queued entries and waits for their acknowledgment.
+func (w *Writer) Sync() error {
+	err := w.batcher.Flush()
+	if ackErr := w.waitAcks(); err == nil {
+		err = ackErr
+	}
+	return err
+}
+
+func (w *Writer) Close() error {
+	err := w.batcher.Close()
+	if ackErr := w.waitAcks(); err == nil {
+		err = ackErr
+	}
+	if w.ack {
+		close(w.done)
+		w.wg.Wait()
+	}
+	return err
+}
+
+func (w *Writer) add(t time.Time, fields []zapcore.Field, p []byte) error {
+	e := event{
+		Time:       formatTime(t),
+		Host:       w.host,
+		Source:     w.source,
+		SourceType: w.sourceType,
+		Index:      w.index,
+		Event:      rawEvent(bytes.TrimRight(p, "\r\n")),
+		Fields:     w.fields,
+	}
+	if values := common.FieldValues(fields, w.fieldNames...); len(values) > 0 {
+		e.Fields = make(map[string]string, len(w.fields)+len(values))
+		for name, value := range w.fields {
+			e.Fields[name] = value
+		}
+		for name, value := range values {
+			e.Fields[name] = value
+		}
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return w.batcher.Add(b, len(b))
+}
+
+// rawEvent keeps JSON encoded entries as objects and sends the others as strings.
+func rawEvent(p []byte) json.RawMessage {
+	if json.Valid(p) {
+		return append(json.RawMessage(nil), p...)
+	}
+	b, _ := json.Marshal(string(p))
+	return b
+}
+
+// formatTime formats t as epoch seconds with milliseconds.
+func formatTime(t time.Time) json.Number {
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return json.Number(strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64))
+}
+
+func (w *Writer) send(items []interface{}) error {
+	var body bytes.Buffer
+	for _, item := range items {
+		body.Write(item.([]byte))
+	}
+	return w.post(body.Bytes(), 0)
+}
+
+// post sends the body, with acks it is then pending until acknowledged.
+func (w *Writer) post(body []byte, resends int) error {
+	resp, err := w.client.Post(body, w.header)
+	if err != nil {
+		return err
+	}
+	if !w.ack {
+		return nil
+	}
+
+	var result struct {
+		Code  int    `json:"code"`
+		Text  string `json:"text"`
+		AckID *int64 `json:"ackId"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("splunk hec: invalid response: %v", err)
+	}
+	if result.AckID == nil {
+		return fmt.Errorf("splunk hec: no ack id in response: %s", result.Text)
+	}
+
+	w.ackMu.Lock()
+	w.pending[*result.AckID] = &pendingAck{
+		body:     body,
+		deadline: w.now().Add(w.ackTimeout),
+		resends:  resends,
+	}
+	w.ackMu.Unlock()
+	return nil
+}
+
+// waitAcks waits until the pending requests are acknowledged or failed and
+// returns the first error since the last call.
+func (w *Writer) waitAcks() error {
+	if !w.ack {
+		return nil
+	}
+	w.ackMu.Lock()
+	defer w.ackMu.Unlock()
+
+	for len(w.pending) > 0 || w.resending > 0 {
+		w.acked.Wait()
+	}
+	err := w.ackErr
+	w.ackErr = nil
+	return err
+}
+
+// pollAcks checks the pending acknowledgments every ack interval, so the
+// batches are sent without waiting for the indexer.
+func (w *Writer) pollAcks() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.ackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.checkAcks()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// checkAcks polls the ack endpoint for the pending requests, the requests
+// not acknowledged in time are sent again.
+func (w *Writer) checkAcks() {
+	w.ackMu.Lock()
+	ids := make([]int64, 0, len(w.pending))
+	for id := range w.pending {
+		ids = append(ids, id)
+	}
+	w.ackMu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	acks, err := w.queryAcks(ids)
+
+	w.ackMu.Lock()
+	var resend []*pendingAck
+	now := w.now()
+	for _, id := range ids {
+		p := w.pending[id]
+		switch {
+		case acks[strconv.FormatInt(id, 10)]:
+			delete(w.pending, id)
+		case now.Before(p.deadline):
+		case p.resends < w.maxResends:
+			delete(w.pending, id)
+			resend = append(resend, p)
+		default:
+			delete(w.pending, id)
+			if err != nil {
+				w.fail(fmt.Errorf("%w: %v", ErrAckTimeout, err))
+			} else {
+				w.fail(ErrAckTimeout)
+			}
+		}
+	}
+	w.resending += len(resend)
+	w.ackMu.Unlock()
+
+	for _, p := range resend {
+		err := w.post(p.body, p.resends+1)
+		w.ackMu.Lock()
+		if err != nil {
+			w.fail(err)
+		}
+		w.resending--
+		w.ackMu.Unlock()
+	}
+
+	w.ackMu.Lock()
+	w.acked.Broadcast()
+	w.ackMu.Unlock()
+}
+
+func (w *Writer) queryAcks(ids []int64) (map[string]bool, error) {
+	body, err := json.Marshal(map[string][]int64{"acks": ids})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.client.Do(http.MethodPost, w.ackURL, body, w.header)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("splunk hec: invalid ack response: %v", err)
+	}
+	return result.Acks, nil
+}
+
+// fail keeps the first error for Sync and reports it to stderr like the
+// batch errors, w.ackMu must be held.
+func (w *Writer) fail(err error) {
+	if w.ackErr == nil {
+		w.ackErr = err
+	}
+	fmt.Fprintf(os.Stderr, "%v write error: %v\n", time.Now(), err)
+}
+
+// newChannel generates a random channel identifier in the GUID format HEC expects.
+func newChannel() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
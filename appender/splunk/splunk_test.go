@@ -0,0 +1,282 @@
+package splunk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewSplunkHEC(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+url: https://127.0.0.1:8088
+token: 00000000-0000-0000-0000-000000000000
+encoder:
+ json:`, false},
+		{"case2", `
+url: https://127.0.0.1:8088
+encoder:
+ json:`, true},
+		{"case3", `
+token: 00000000-0000-0000-0000-000000000000
+encoder:
+ json:`, true},
+		{"case4", `
+url: https://127.0.0.1:8088/services/collector
+token: 00000000-0000-0000-0000-000000000000
+ack: true
+encoder:
+ json:`, false},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+// collector is a stand-in for the HEC endpoints. With acks enabled every
+// event request gets the next ack id, acknowledged after pending polls
+// unless it is lost.
+type collector struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+
+	ack     bool
+	pending int
+	lost    map[int]bool
+	nextID  int
+	polls   int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, _ := ioutil.ReadAll(req.Body)
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, string(data))
+
+	if len(c.statuses) > 0 {
+		var status int
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	switch req.URL.Path {
+	case eventPath, collectorPath:
+		if c.ack {
+			fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, c.nextID)
+			c.nextID++
+			return
+		}
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	case ackPath:
+		c.polls++
+		var acks struct {
+			Acks []int `json:"acks"`
+		}
+		_ = json.Unmarshal(data, &acks)
+		result := map[string]bool{}
+		for _, id := range acks.Acks {
+			result[strconv.Itoa(id)] = c.polls > c.pending && !c.lost[id]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": result})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (c *collector) get() ([]*http.Request, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests, c.bodies
+}
+
+func testConfig(url string) Config {
+	config := DefaultConfig()
+	config.URL = url
+	config.Token = "token"
+	config.Host = "host"
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	config.AckInterval = time.Millisecond
+	return config
+}
+
+func TestWriter_events(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Source = "app"
+	config.SourceType = "_json"
+	config.Index = "main"
+	config.Fields = map[string]string{"env": "prod"}
+	config.FieldNames = []string{"user"}
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ent := zapcore.Entry{Time: time.Unix(1600000000, 123456789)}
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("user", "bob")}, []byte(`{"msg":"hello"}`+"\n")))
+	_, err = w.Write([]byte("plain text\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := c.get()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, eventPath, requests[0].URL.Path)
+	assert.Equal(t, "Splunk token", requests[0].Header.Get("Authorization"))
+
+	dec := json.NewDecoder(strings.NewReader(bodies[0]))
+	var events []map[string]interface{}
+	for dec.More() {
+		var e map[string]interface{}
+		assert.NoError(t, dec.Decode(&e))
+		events = append(events, e)
+	}
+	assert.Len(t, events, 2)
+	assert.Equal(t, map[string]interface{}{
+		"time":       1600000000.123,
+		"host":       "host",
+		"source":     "app",
+		"sourcetype": "_json",
+		"index":      "main",
+		"event":      map[string]interface{}{"msg": "hello"},
+		"fields":     map[string]interface{}{"env": "prod", "user": "bob"},
+	}, events[0])
+	assert.Equal(t, "plain text", events[1]["event"])
+	assert.Equal(t, map[string]interface{}{"env": "prod"}, events[1]["fields"])
+}
+
+func TestWriter_ack(t *testing.T) {
+	c := &collector{ack: true, pending: 2}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Ack = true
+	config.Channel = "FE0ECFAD-13D5-401B-847D-77833BD77131"
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"hello"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := c.get()
+	assert.Len(t, requests, 4)
+	assert.Equal(t, ackPath, requests[1].URL.Path)
+	assert.Equal(t, config.Channel, requests[1].URL.Query().Get("channel"))
+	assert.Equal(t, config.Channel, requests[0].Header.Get("X-Splunk-Request-Channel"))
+	assert.Equal(t, `{"acks":[0]}`, bodies[3])
+}
+
+func TestWriter_ackTimeout(t *testing.T) {
+	c := &collector{ack: true, pending: 1000}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Ack = true
+	config.AckTimeout = 20 * time.Millisecond
+	config.MaxRetries = 2
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"hello"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, ErrAckTimeout, w.Sync())
+
+	requests, bodies := c.get()
+	assert.Len(t, requests[0].Header.Get("X-Splunk-Request-Channel"), 36)
+	var sent []string
+	for i, req := range requests {
+		if req.URL.Path == eventPath {
+			sent = append(sent, bodies[i])
+		}
+	}
+	// sent once and again MaxRetries times
+	assert.Len(t, sent, 3)
+	assert.Equal(t, sent[0], sent[2])
+}
+
+func TestWriter_ackResend(t *testing.T) {
+	c := &collector{ack: true, lost: map[int]bool{0: true}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Ack = true
+	config.AckTimeout = 20 * time.Millisecond
+	config.Batch.Size = 1
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	// the batches are sent without waiting for the acknowledgments
+	_, err = w.Write([]byte(`{"msg":"lost"}` + "\n"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(`{"msg":"acked"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	requests, bodies := c.get()
+	var sent []string
+	for i, req := range requests {
+		if req.URL.Path == eventPath {
+			sent = append(sent, strings.TrimSpace(bodies[i]))
+		}
+	}
+	if assert.Len(t, sent, 3) {
+		assert.Contains(t, sent[0], "lost")
+		assert.Contains(t, sent[1], "acked")
+		assert.Equal(t, sent[0], sent[2])
+	}
+}
+
+func TestWriter_retry(t *testing.T) {
+	c := &collector{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	w, err := NewWriter(testConfig(srv.URL + "/services/collector"))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte(`{"msg":"hello"}` + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	requests, _ := c.get()
+	assert.Len(t, requests, 2)
+	assert.Equal(t, collectorPath, requests[1].URL.Path)
+}
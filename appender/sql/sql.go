@@ -0,0 +1,272 @@
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	// Driver is the name of a database/sql driver, the application must
+	// import it.
+	Driver string `logos-config:"driver" logos-validate:"required"`
+	DSN    string `logos-config:"dsn" logos-validate:"required"`
+
+	Table   string  `logos-config:"table" logos-validate:"required"`
+	Columns Columns `logos-config:"columns"`
+	// Placeholder is the bind parameter style of the driver:
+	// question (?), dollar ($1), colon (:1) or at (@p1).
+	Placeholder string `logos-config:"placeholder" logos-validate:"logos.oneof=question dollar colon at"`
+	// CreateTable creates the table if it does not exist.
+	CreateTable bool `logos-config:"create_table"`
+
+	Batch batch.Config `logos-config:"batch"`
+}
+
+// Columns maps the entry parts to the table columns, an empty name skips the part.
+type Columns struct {
+	Time    string `logos-config:"time"`
+	Level   string `logos-config:"level"`
+	Logger  string `logos-config:"logger"`
+	Message string `logos-config:"message"`
+	Caller  string `logos-config:"caller"`
+	// Fields is a JSON object of the entry fields.
+	Fields string `logos-config:"fields"`
+	// Encoded is the entry formatted by the appender encoder.
+	Encoded string `logos-config:"encoded"`
+}
+
+var (
+	defaultConfig = Config{
+		Table: "logs",
+		Columns: Columns{
+			Time:    "ts",
+			Level:   "level",
+			Logger:  "logger",
+			Message: "message",
+			Caller:  "caller",
+			Fields:  "fields",
+		},
+		Placeholder: "question",
+		Batch: batch.Config{
+			Size:     100,
+			Interval: time.Second,
+		},
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type column struct {
+	name     string
+	sqlType  string
+	value    func(r *row) interface{}
+	nullable bool
+}
+
+type row struct {
+	time    time.Time
+	level   string
+	logger  string
+	message string
+	caller  string
+	fields  string
+	encoded string
+}
+
+// Writer inserts entries into a table, one transaction per batch.
+type Writer struct {
+	db      *sql.DB
+	columns []column
+	insert  string
+	batcher *batch.Batcher
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if !identifier.MatchString(cfg.Table) {
+		return nil, fmt.Errorf("invalid sql table name %q", cfg.Table)
+	}
+	columns, err := cfg.Columns.columns()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		db:      db,
+		columns: columns,
+		insert:  insertStatement(cfg.Table, columns, cfg.Placeholder),
+	}
+	if cfg.CreateTable {
+		if _, err := db.Exec(createStatement(cfg.Table, columns)); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	w.batcher = batch.New(cfg.Batch, w.send, nil)
+	return w, nil
+}
+
+func (c Columns) columns() ([]column, error) {
+	all := []column{
+		{c.Time, "TIMESTAMP", func(r *row) interface{} { return r.time }, false},
+		{c.Level, "VARCHAR(16)", func(r *row) interface{} { return r.level }, false},
+		{c.Logger, "VARCHAR(255)", func(r *row) interface{} { return r.logger }, true},
+		{c.Message, "TEXT", func(r *row) interface{} { return r.message }, true},
+		{c.Caller, "VARCHAR(255)", func(r *row) interface{} { return r.caller }, true},
+		{c.Fields, "TEXT", func(r *row) interface{} { return r.fields }, true},
+		{c.Encoded, "TEXT", func(r *row) interface{} { return r.encoded }, true},
+	}
+
+	columns := make([]column, 0, len(all))
+	for _, col := range all {
+		if len(col.name) == 0 {
+			continue
+		}
+		if !identifier.MatchString(col.name) {
+			return nil, fmt.Errorf("invalid sql column name %q", col.name)
+		}
+		columns = append(columns, col)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no sql columns configured")
+	}
+	return columns, nil
+}
+
+func insertStatement(table string, columns []column, placeholder string) string {
+	names := make([]string, len(columns))
+	params := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+		n := strconv.Itoa(i + 1)
+		switch placeholder {
+		case "dollar":
+			params[i] = "$" + n
+		case "colon":
+			params[i] = ":" + n
+		case "at":
+			params[i] = "@p" + n
+		default:
+			params[i] = "?"
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), strings.Join(params, ", "))
+}
+
+func createStatement(table string, columns []column) string {
+	defs := make([]string, len(columns))
+	for i, col := range columns {
+		defs[i] = col.name + " " + col.sqlType
+		if !col.nullable {
+			defs[i] += " NOT NULL"
+		}
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(defs, ", "))
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	r := &row{
+		time:    time.Now(),
+		level:   zapcore.InfoLevel.String(),
+		message: strings.TrimRight(string(p), "\r\n"),
+		encoded: strings.TrimRight(string(p), "\r\n"),
+	}
+	if err := w.batcher.Add(r, len(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	r := &row{
+		time:    ent.Time,
+		level:   ent.Level.String(),
+		logger:  ent.LoggerName,
+		message: ent.Message,
+		encoded: strings.TrimRight(string(p), "\r\n"),
+	}
+	if ent.Caller.Defined {
+		r.caller = ent.Caller.TrimmedPath()
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	b, err := json.Marshal(enc.Fields)
+	if err != nil {
+		return err
+	}
+	r.fields = string(b)
+
+	return w.batcher.Add(r, len(p))
+}
+
+// Sync inserts the queued entries.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	err := w.batcher.Close()
+	if dbErr := w.db.Close(); err == nil {
+		err = dbErr
+	}
+	return err
+}
+
+// send inserts the batch in one transaction.
+func (w *Writer) send(items []interface{}) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(w.insert)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	args := make([]interface{}, len(w.columns))
+	for _, item := range items {
+		r := item.(*row)
+		for i, col := range w.columns {
+			args[i] = col.value(r)
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	_ = stmt.Close()
+	return tx.Commit()
+}
@@ -0,0 +1,329 @@
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recorder is a database/sql driver recording the executed statements
+// per database name, standing in for a real database in tests.
+type recorder struct {
+	mu  sync.Mutex
+	dbs map[string]*database
+}
+
+type database struct {
+	execs   []exec
+	commits int
+	// failOn makes the n-th exec fail
+	failOn    int
+	rollbacks int
+}
+
+type exec struct {
+	query string
+	args  []driver.Value
+}
+
+var testDriver = &recorder{dbs: map[string]*database{}}
+
+func init() {
+	sql.Register("logos_test", testDriver)
+}
+
+func (r *recorder) db(name string) *database {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dbs[name] == nil {
+		r.dbs[name] = &database{}
+	}
+	return r.dbs[name]
+}
+
+func (r *recorder) Open(name string) (driver.Conn, error) {
+	return &conn{r: r, db: r.db(name)}, nil
+}
+
+type conn struct {
+	r  *recorder
+	db *database
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *conn) Commit() error {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.db.commits++
+	return nil
+}
+
+func (c *conn) Rollback() error {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.db.rollbacks++
+	return nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.r.mu.Lock()
+	defer s.c.r.mu.Unlock()
+	db := s.c.db
+	db.execs = append(db.execs, exec{s.query, args})
+	if db.failOn > 0 && len(db.execs) == db.failOn {
+		return nil, errors.New("constraint failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, io.EOF
+}
+
+// reset forgets the databases of the previous tests, so tests also pass
+// when run several times.
+func (r *recorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dbs = map[string]*database{}
+}
+
+func (r *recorder) get(name string) database {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.dbs[name]
+}
+
+func TestNewSQL(t *testing.T) {
+	testDriver.reset()
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+driver: logos_test
+dsn: new1
+encoder:
+ json:`, false},
+		{"case2", `
+driver: unknown
+dsn: new2
+encoder:
+ json:`, true},
+		{"case3", `
+driver: logos_test
+dsn: new3
+table: "logs; DROP TABLE users"
+encoder:
+ json:`, true},
+		{"case4", `
+driver: logos_test
+dsn: new4
+placeholder: dollar
+create_table: true
+table: audit.events
+columns:
+  caller: ""
+  encoded: entry
+encoder:
+ json:`, false},
+		{"case5", `
+driver: logos_test
+dsn: new5
+placeholder: percent
+encoder:
+ json:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+
+	assert.Equal(t, []exec{{
+		query: "CREATE TABLE IF NOT EXISTS audit.events (ts TIMESTAMP NOT NULL, level VARCHAR(16) NOT NULL, " +
+			"logger VARCHAR(255), message TEXT, fields TEXT, entry TEXT)",
+		args: []driver.Value{},
+	}}, testDriver.get("new4").execs)
+}
+
+var entryTime = time.Date(2020, 9, 3, 7, 0, 0, 0, time.UTC)
+
+func TestWriter_batch(t *testing.T) {
+	testDriver.reset()
+	config := DefaultConfig()
+	config.Driver = "logos_test"
+	config.DSN = "batch"
+	config.Batch.Size = 2
+	config.Batch.Interval = 0
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	ent := zapcore.Entry{
+		Time:       entryTime,
+		Level:      zapcore.WarnLevel,
+		LoggerName: "audit",
+		Message:    "login",
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/auth/login.go", 12, true),
+	}
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("user", "bob"), zap.Int("attempt", 1)}, []byte("{}\n")))
+	assert.NoError(t, w.WriteEntry(ent, nil, []byte("{}\n")))
+	_, err = w.Write([]byte("plain\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	db := testDriver.get("batch")
+	assert.Equal(t, 2, db.commits)
+	assert.Len(t, db.execs, 3)
+	assert.Equal(t, "INSERT INTO logs (ts, level, logger, message, caller, fields) VALUES (?, ?, ?, ?, ?, ?)", db.execs[0].query)
+	assert.Equal(t, []driver.Value{entryTime, "warn", "audit", "login", "auth/login.go:12", `{"attempt":1,"user":"bob"}`}, db.execs[0].args)
+	assert.Equal(t, "{}", db.execs[1].args[5])
+	assert.Equal(t, "plain", db.execs[2].args[3])
+}
+
+func TestWriter_placeholders(t *testing.T) {
+	columns, err := Columns{Time: "ts", Message: "msg"}.columns()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "INSERT INTO logs (ts, msg) VALUES ($1, $2)", insertStatement("logs", columns, "dollar"))
+	assert.Equal(t, "INSERT INTO logs (ts, msg) VALUES (:1, :2)", insertStatement("logs", columns, "colon"))
+	assert.Equal(t, "INSERT INTO logs (ts, msg) VALUES (@p1, @p2)", insertStatement("logs", columns, "at"))
+
+	_, err = Columns{}.columns()
+	assert.Error(t, err)
+}
+
+func TestWriter_rollback(t *testing.T) {
+	testDriver.reset()
+	config := DefaultConfig()
+	config.Driver = "logos_test"
+	config.DSN = "rollback"
+	config.Batch.Size = 10
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+	testDriver.db("rollback").failOn = 2
+
+	for i := 0; i < 3; i++ {
+		_, err = w.Write([]byte("entry\n"))
+		assert.NoError(t, err)
+	}
+	assert.EqualError(t, w.Sync(), "constraint failed")
+
+	db := testDriver.get("rollback")
+	assert.Equal(t, 0, db.commits)
+	assert.Equal(t, 1, db.rollbacks)
+	assert.Len(t, db.execs, 2)
+}
+
+func TestWriter_sqlite(t *testing.T) {
+	if !hasDriver("sqlite3") {
+		t.Skip("sqlite3 driver is not registered, run with -tags sqlite")
+	}
+	dsn := filepath.Join(t.TempDir(), "logs.db")
+
+	cfg, err := common.NewConfigFrom(`
+driver: sqlite3
+dsn: ` + dsn + `
+table: app_logs
+create_table: true
+columns:
+  encoded: entry
+batch:
+  size: 10
+encoder:
+ json:`)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ws, err := New(cfg)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	w := ws.(*Writer)
+
+	ent := zapcore.Entry{
+		Time:       entryTime,
+		Level:      zapcore.ErrorLevel,
+		LoggerName: "audit",
+		Message:    "login",
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/auth/login.go", 12, true),
+	}
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.String("user", "bob")}, []byte(`{"msg":"login"}`+"\n")))
+	_, err = w.Write([]byte("plain\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	db, err := sql.Open("sqlite3", dsn)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT ts, level, logger, message, caller, fields, entry FROM app_logs ORDER BY rowid")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer rows.Close()
+
+	type logRow struct {
+		ts                                            time.Time
+		level, logger, message, caller, fields, entry string
+	}
+	var got []logRow
+	for rows.Next() {
+		var r logRow
+		assert.NoError(t, rows.Scan(&r.ts, &r.level, &r.logger, &r.message, &r.caller, &r.fields, &r.entry))
+		got = append(got, r)
+	}
+	assert.NoError(t, rows.Err())
+
+	if assert.Len(t, got, 2) {
+		assert.True(t, entryTime.Equal(got[0].ts))
+		assert.Equal(t, logRow{got[0].ts, "error", "audit", "login", "auth/login.go:12", `{"user":"bob"}`, `{"msg":"login"}`}, got[0])
+		assert.Equal(t, "info", got[1].level)
+		assert.Equal(t, "plain", got[1].message)
+		assert.Equal(t, "plain", got[1].entry)
+	}
+}
+
+func hasDriver(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build sqlite
+// +build sqlite
+
+package sql
+
+// The sqlite3 driver needs cgo, TestWriter_sqlite runs with go test -tags sqlite.
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
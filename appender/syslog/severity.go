@@ -0,0 +1,94 @@
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	severityEmerg = iota
+	severityAlert
+	severityCrit
+	severityErr
+	severityWarning
+	severityNotice
+	severityInfo
+	severityDebug
+)
+
+var severityNames = map[string]int{
+	"emerg":   severityEmerg,
+	"alert":   severityAlert,
+	"crit":    severityCrit,
+	"err":     severityErr,
+	"error":   severityErr,
+	"warning": severityWarning,
+	"warn":    severityWarning,
+	"notice":  severityNotice,
+	"info":    severityInfo,
+	"debug":   severityDebug,
+}
+
+var defaultSeverities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  severityDebug,
+	zapcore.InfoLevel:   severityInfo,
+	zapcore.WarnLevel:   severityWarning,
+	zapcore.ErrorLevel:  severityErr,
+	zapcore.DPanicLevel: severityCrit,
+	zapcore.PanicLevel:  severityAlert,
+	zapcore.FatalLevel:  severityEmerg,
+}
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// parseSeverities merges the configured level to severity mapping
+// with the default one. Severities are names or numbers 0-7.
+func parseSeverities(config map[string]string) (map[zapcore.Level]int, error) {
+	severities := make(map[zapcore.Level]int, len(defaultSeverities))
+	for level, severity := range defaultSeverities {
+		severities[level] = severity
+	}
+
+	for levelName, severityName := range config {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(levelName)); err != nil {
+			return nil, err
+		}
+
+		severity, ok := severityNames[strings.ToLower(severityName)]
+		if !ok {
+			n, err := strconv.Atoi(severityName)
+			if err != nil || n < severityEmerg || n > severityDebug {
+				return nil, fmt.Errorf("unknown syslog severity %q", severityName)
+			}
+			severity = n
+		}
+		severities[level] = severity
+	}
+
+	return severities, nil
+}
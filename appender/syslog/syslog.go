@@ -0,0 +1,303 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	// Network is one of udp, tcp, unix or unixgram. The default local
+	// network looks for the syslog socket at /dev/log and alike.
+	Network string `logos-config:"network" logos-validate:"logos.oneof=local udp tcp unix unixgram"`
+	Address string `logos-config:"address"`
+
+	// Format is the message format, rfc5424 or rfc3164.
+	Format string `logos-config:"format" logos-validate:"logos.oneof=rfc5424 rfc3164"`
+
+	// Framing is used for tcp connections, octet_counting (RFC 6587 3.4.1)
+	// or newline (RFC 6587 3.4.2). Local and unix stream sockets, read by
+	// rsyslog or journald, are always newline framed.
+	Framing string `logos-config:"framing" logos-validate:"logos.oneof=octet_counting newline"`
+
+	Facility string `logos-config:"facility"`
+	Hostname string `logos-config:"hostname"`
+	AppName  string `logos-config:"app_name"`
+	MsgID    string `logos-config:"msg_id"`
+
+	// Severities overrides the severity for a level, e.g. `warn: notice`.
+	Severities map[string]string `logos-config:"severities"`
+
+	Timeout time.Duration `logos-config:"timeout"`
+	// TLS is supported by the tcp and unix networks.
+	TLS common.TLSConfig `logos-config:"tls"`
+}
+
+var (
+	defaultConfig = Config{
+		Network:  "local",
+		Format:   RFC5424,
+		Framing:  OctetCounting,
+		Facility: "user",
+		MsgID:    "-",
+		Timeout:  5 * time.Second,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+const (
+	RFC5424 = "rfc5424"
+	RFC3164 = "rfc3164"
+
+	OctetCounting = "octet_counting"
+	Newline       = "newline"
+)
+
+// ErrClosed is returned by the writes to a closed Writer.
+var ErrClosed = errors.New("syslog writer closed")
+
+var localAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Writer sends encoded entries to a syslog server.
+type Writer struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+
+	format     string
+	framing    string
+	facility   int
+	hostname   string
+	appName    string
+	procID     string
+	msgID      string
+	severities map[zapcore.Level]int
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	facility, ok := facilities[strings.ToLower(cfg.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+
+	severities, err := parseSeverities(cfg.Severities)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && cfg.Network != "tcp" && cfg.Network != "unix" {
+		return nil, fmt.Errorf("tls is not supported for syslog network %q", cfg.Network)
+	}
+
+	w := &Writer{
+		network:    cfg.Network,
+		address:    cfg.Address,
+		tlsConfig:  tlsConfig,
+		timeout:    cfg.Timeout,
+		format:     cfg.Format,
+		framing:    cfg.Framing,
+		facility:   facility,
+		hostname:   cfg.Hostname,
+		appName:    cfg.AppName,
+		procID:     strconv.Itoa(os.Getpid()),
+		msgID:      cfg.MsgID,
+		severities: severities,
+	}
+
+	if len(w.hostname) == 0 {
+		if w.hostname, err = os.Hostname(); err != nil {
+			return nil, err
+		}
+	}
+	if len(w.appName) == 0 {
+		w.appName = filepath.Base(os.Args[0])
+	}
+	if len(w.msgID) == 0 {
+		w.msgID = "-"
+	}
+
+	if w.network == "local" && len(w.address) > 0 {
+		return nil, errors.New("syslog address requires network")
+	}
+	if w.network != "local" && len(w.address) == 0 {
+		return nil, fmt.Errorf("syslog network %q requires address", w.network)
+	}
+
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.send(zapcore.InfoLevel, time.Now(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, _ []zapcore.Field, p []byte) error {
+	return w.send(ent.Level, ent.Time, p)
+}
+
+func (w *Writer) Sync() error {
+	return nil
+}
+
+// Close closes the connection, the later writes fail with ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *Writer) send(level zapcore.Level, t time.Time, p []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	msg := w.message(level, t, p)
+
+	// retry once on a fresh connection, the server may have closed the old one
+	for attempt := 0; ; attempt++ {
+		err := w.writeMessage(msg)
+		if err == nil {
+			return nil
+		}
+		if w.conn != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+func (w *Writer) writeMessage(msg []byte) error {
+	if w.conn == nil {
+		conn, err := w.dial()
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+
+	if w.timeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+
+	if isStream(w.conn) {
+		if w.network == "tcp" && w.framing == OctetCounting {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		} else {
+			msg = append(msg, '\n')
+		}
+	}
+
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+func (w *Writer) dial() (net.Conn, error) {
+	if w.network == "local" {
+		return dialLocal(w.timeout)
+	}
+
+	dialer := &net.Dialer{Timeout: w.timeout}
+	if w.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, w.network, w.address, w.tlsConfig)
+	}
+	return dialer.Dial(w.network, w.address)
+}
+
+func dialLocal(timeout time.Duration) (net.Conn, error) {
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, address := range localAddresses {
+			conn, err := net.DialTimeout(network, address, timeout)
+			if err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("local syslog server not found")
+}
+
+func isStream(conn net.Conn) bool {
+	switch conn.LocalAddr().Network() {
+	case "udp", "unixgram":
+		return false
+	}
+	return true
+}
+
+// message builds the syslog message for the encoded entry p.
+func (w *Writer) message(level zapcore.Level, t time.Time, p []byte) []byte {
+	pri := w.facility*8 + w.severity(level)
+	p = trimLineEnding(p)
+
+	var header string
+	switch w.format {
+	case RFC3164:
+		header = fmt.Sprintf("<%d>%s %s %s[%s]: ",
+			pri, t.Format(time.Stamp), w.hostname, w.appName, w.procID)
+	default:
+		header = fmt.Sprintf("<%d>1 %s %s %s %s %s - ",
+			pri, t.Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, w.appName, w.procID, w.msgID)
+	}
+
+	msg := make([]byte, 0, len(header)+len(p))
+	msg = append(msg, header...)
+	return append(msg, p...)
+}
+
+func (w *Writer) severity(level zapcore.Level) int {
+	if s, ok := w.severities[level]; ok {
+		return s
+	}
+	return severityDebug
+}
+
+func trimLineEnding(p []byte) []byte {
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+	return p
+}
@@ -0,0 +1,242 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewSyslog(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+network: udp
+address: 127.0.0.1:514
+encoder:
+ json:`, false},
+		{"case2", `
+network: udp
+encoder:
+ json:`, true},
+		{"case3", `
+network: udp
+address: 127.0.0.1:514
+facility: unknown
+encoder:
+ json:`, true},
+		{"case4", `
+network: tcp
+address: 127.0.0.1:514
+severities:
+  warn: notice
+  error: 2
+encoder:
+ json:`, false},
+		{"case5", `
+network: tcp
+address: 127.0.0.1:514
+severities:
+  warn: loud
+encoder:
+ json:`, true},
+		{"case6", `
+network: http
+address: 127.0.0.1:514
+encoder:
+ json:`, true},
+		{"case7", `
+network: udp
+address: 127.0.0.1:514
+tls:
+ enabled: true
+encoder:
+ json:`, true},
+		{"case8", `
+network: unixgram
+address: /dev/log
+tls:
+ enabled: true
+encoder:
+ json:`, true},
+		{"case9", `
+tls:
+ enabled: true
+encoder:
+ json:`, true},
+		{"case10", `
+network: tcp
+address: 127.0.0.1:6514
+tls:
+ enabled: true
+encoder:
+ json:`, false},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		_, err = New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+var entryTime = time.Date(2021, 3, 4, 5, 6, 7, 8000, time.UTC)
+
+func newTestWriter(t *testing.T, cfg Config) *Writer {
+	cfg.Hostname = "host"
+	cfg.AppName = "app"
+	w, err := NewWriter(cfg)
+	assert.NoError(t, err)
+	w.procID = "42"
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func TestWriter_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	cfg := DefaultConfig()
+	cfg.Network = "udp"
+	cfg.Address = conn.LocalAddr().String()
+	cfg.Facility = "local0"
+	cfg.MsgID = "ID47"
+	w := newTestWriter(t, cfg)
+
+	err = w.WriteEntry(zapcore.Entry{Level: zapcore.WarnLevel, Time: entryTime}, nil, []byte("{\"msg\":\"hello\"}\n"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `<132>1 2021-03-04T05:06:07.000008Z host app 42 ID47 - {"msg":"hello"}`, string(buf[:n]))
+}
+
+func TestWriter_tcpFraming(t *testing.T) {
+	tests := []struct {
+		framing string
+		read    func(r *bufio.Reader) (string, error)
+	}{
+		{OctetCounting, func(r *bufio.Reader) (string, error) {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return "", err
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(size))
+			if err != nil {
+				return "", err
+			}
+			msg := make([]byte, n)
+			_, err = io.ReadFull(r, msg)
+			return string(msg), err
+		}},
+		{Newline, func(r *bufio.Reader) (string, error) {
+			msg, err := r.ReadString('\n')
+			return strings.TrimSuffix(msg, "\n"), err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.framing, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err)
+			defer ln.Close()
+
+			cfg := DefaultConfig()
+			cfg.Network = "tcp"
+			cfg.Address = ln.Addr().String()
+			cfg.Format = RFC3164
+			cfg.Framing = tt.framing
+			w := newTestWriter(t, cfg)
+
+			_, err = w.Write([]byte("first\n"))
+			assert.NoError(t, err)
+			err = w.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Time: entryTime}, nil, []byte("second\n"))
+			assert.NoError(t, err)
+
+			conn, err := ln.Accept()
+			assert.NoError(t, err)
+			defer conn.Close()
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			r := bufio.NewReader(conn)
+
+			first, err := tt.read(r)
+			assert.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(`^<14>\w{3} [ \d]\d \d\d:\d\d:\d\d host app\[42\]: first$`), first)
+
+			second, err := tt.read(r)
+			assert.NoError(t, err)
+			assert.Equal(t, "<11>Mar  4 05:06:07 host app[42]: second", second)
+		})
+	}
+}
+
+func TestWriter_unixgram(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	cfg := DefaultConfig()
+	cfg.Network = "unixgram"
+	cfg.Address = addr
+	cfg.Severities = map[string]string{"info": "notice"}
+	w := newTestWriter(t, cfg)
+
+	err = w.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: entryTime}, nil, []byte("hello\n"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "<13>1 2021-03-04T05:06:07.000008Z host app 42 - - hello", string(buf[:n]))
+}
+
+func TestWriter_localStream(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	ln, err := net.Listen("unix", addr)
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	saved := localAddresses
+	localAddresses = []string{addr}
+	defer func() { localAddresses = saved }()
+
+	cfg := DefaultConfig()
+	cfg.Format = RFC3164
+	w := newTestWriter(t, cfg)
+	assert.Equal(t, OctetCounting, cfg.Framing)
+
+	err = w.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: entryTime}, nil, []byte("first\n"))
+	assert.NoError(t, err)
+	err = w.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: entryTime}, nil, []byte("second\n"))
+	assert.NoError(t, err)
+
+	conn, err := ln.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+
+	for _, msg := range []string{"first", "second"} {
+		line, err := r.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "<14>Mar  4 05:06:07 host app[42]: "+msg+"\n", line)
+	}
+}
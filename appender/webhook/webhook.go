@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/khorevaa/logos/internal/batch"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/khorevaa/logos/internal/httpclient"
+	"github.com/khorevaa/logos/internal/notify"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultTemplate posts the encoded entries as a Slack and Mattermost
+// compatible message.
+const DefaultTemplate = `{"text": {{ json .Text }}}`
+
+type Config struct {
+	httpclient.Config `logos-config:",inline"`
+
+	// Template is a text/template producing the request body.
+	Template string `logos-config:"template" logos-validate:"required"`
+
+	// Aggregate groups the entries written within the window into one
+	// message of at most MaxEntries entries.
+	Aggregate  time.Duration `logos-config:"aggregate"`
+	MaxEntries int           `logos-config:"max_entries" logos-validate:"min=1"`
+
+	// RateLimit is the maximum number of messages sent per RateWindow,
+	// 0 means no limit. The entries over the limit are suppressed and
+	// counted in the next message.
+	RateLimit  int           `logos-config:"rate_limit" logos-validate:"min=0"`
+	RateWindow time.Duration `logos-config:"rate_window"`
+}
+
+var (
+	defaultConfig = Config{
+		Config:     httpclient.DefaultConfig,
+		Template:   DefaultTemplate,
+		Aggregate:  5 * time.Second,
+		MaxEntries: 20,
+		RateLimit:  10,
+		RateWindow: time.Minute,
+	}
+)
+
+func DefaultConfig() Config {
+	return defaultConfig
+}
+
+// Entry is a log entry in the template data.
+type Entry = notify.Entry
+
+// Data is passed to the template.
+type Data struct {
+	Entries []Entry
+	// Text is the encoded entries, one per line.
+	Text string
+	// Suppressed is the number of entries dropped by the rate limit
+	// since the previous message.
+	Suppressed int
+}
+
+// Writer posts templated messages to a webhook.
+type Writer struct {
+	client   *httpclient.Client
+	header   http.Header
+	template *template.Template
+	batcher  *batch.Batcher
+
+	mu         sync.Mutex
+	limiter    notify.Limiter
+	suppressed int
+}
+
+func New(v *common.Config) (zapcore.WriteSyncer, error) {
+	cfg := DefaultConfig()
+	if err := v.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	return NewWriter(cfg)
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	client, err := httpclient.New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("webhook").Funcs(notify.Funcs).Parse(cfg.Template)
+	if err != nil {
+		return nil, fmt.Errorf("webhook template: %v", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	w := &Writer{
+		client:   client,
+		header:   header,
+		template: tmpl,
+		limiter: notify.Limiter{
+			Limit:  cfg.RateLimit,
+			Window: cfg.RateWindow,
+		},
+	}
+	w.batcher = batch.New(batch.Config{
+		Size:     cfg.MaxEntries,
+		Interval: cfg.Aggregate,
+	}, w.send, nil)
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if err := w.batcher.Add(notify.TextEntry(p), len(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) error {
+	return w.batcher.Add(notify.NewEntry(ent, fields, p), len(p))
+}
+
+// Sync sends the aggregated entries.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+func (w *Writer) Close() error {
+	return w.batcher.Close()
+}
+
+func (w *Writer) send(items []interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.limiter.Allow() {
+		w.suppressed += len(items)
+		return nil
+	}
+
+	data := Data{
+		Entries:    make([]Entry, 0, len(items)),
+		Suppressed: w.suppressed,
+	}
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		e := item.(*Entry)
+		data.Entries = append(data.Entries, *e)
+		lines = append(lines, e.Encoded)
+	}
+	data.Text = strings.Join(lines, "\n")
+	if data.Suppressed > 0 {
+		data.Text += fmt.Sprintf("\n(%d more entries suppressed)", data.Suppressed)
+	}
+
+	var body bytes.Buffer
+	if err := w.template.Execute(&body, data); err != nil {
+		return fmt.Errorf("webhook template: %v", err)
+	}
+	if _, err := w.client.Post(body.Bytes(), w.header); err != nil {
+		return err
+	}
+	w.suppressed = 0
+	return nil
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewWebhook(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+url: https://hooks.slack.com/services/T000/B000/XXXX
+encoder:
+ console:`, false},
+		{"case2", `
+encoder:
+ console:`, true},
+		{"case3", `
+url: https://hooks.slack.com/services/T000/B000/XXXX
+template: '{"text": {{ .Text }'
+encoder:
+ console:`, true},
+		{"case4", `
+url: https://hooks.slack.com/services/T000/B000/XXXX
+max_entries: 0
+encoder:
+ console:`, true},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		w, err := New(cfg)
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+		if err == nil {
+			_ = w.(*Writer).Close()
+		}
+	}
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	bodies   []string
+	statuses []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, _ := ioutil.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(data))
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bodies
+}
+
+func testConfig(url string) Config {
+	config := DefaultConfig()
+	config.URL = url
+	config.Aggregate = time.Hour
+	config.BackoffMin = time.Millisecond
+	config.BackoffMax = time.Millisecond
+	return config
+}
+
+func writeError(t *testing.T, w *Writer, msg string) {
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, LoggerName: "db", Message: msg}
+	assert.NoError(t, w.WriteEntry(ent, []zapcore.Field{zap.Int("attempt", 3)}, []byte("ERROR db "+msg+"\n")))
+}
+
+func TestWriter_aggregate(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	w, err := NewWriter(testConfig(srv.URL))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeError(t, w, "connection lost")
+	writeError(t, w, "reconnect failed")
+	assert.NoError(t, w.Sync())
+
+	bodies := r.get()
+	assert.Len(t, bodies, 1)
+	var msg map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(bodies[0]), &msg))
+	assert.Equal(t, "ERROR db connection lost\nERROR db reconnect failed", msg["text"])
+}
+
+func TestWriter_template(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.Template = `{"title": "{{ len .Entries }} errors", "sections": [` +
+		`{{ range $i, $e := .Entries }}{{ if $i }},{{ end }}` +
+		`{"text": {{ json $e.Message }}, "level": "{{ lower $e.Level }}", "attempt": {{ index $e.Fields "attempt" }}}` +
+		`{{ end }}]}`
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	writeError(t, w, `quoted "name"`)
+	assert.NoError(t, w.Sync())
+
+	assert.JSONEq(t, `{"title": "1 errors", "sections": [{"text": "quoted \"name\"", "level": "error", "attempt": 3}]}`, r.get()[0])
+}
+
+func TestWriter_maxEntries(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.MaxEntries = 2
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	for i := 0; i < 3; i++ {
+		writeError(t, w, "failed")
+	}
+	assert.NoError(t, w.Sync())
+	assert.Len(t, r.get(), 2)
+}
+
+func TestWriter_rateLimit(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	config := testConfig(srv.URL)
+	config.RateLimit = 1
+	config.RateWindow = 50 * time.Millisecond
+	w, err := NewWriter(config)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	for _, msg := range []string{"first", "second", "third"} {
+		writeError(t, w, msg)
+		assert.NoError(t, w.Sync())
+	}
+	assert.Len(t, r.get(), 1)
+
+	time.Sleep(60 * time.Millisecond)
+	writeError(t, w, "fourth")
+	assert.NoError(t, w.Sync())
+
+	bodies := r.get()
+	assert.Len(t, bodies, 2)
+	var msg map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(bodies[1]), &msg))
+	assert.Equal(t, "ERROR db fourth\n(2 more entries suppressed)", msg["text"])
+}
+
+func TestWriter_retry(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusTooManyRequests, http.StatusBadGateway}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	w, err := NewWriter(testConfig(srv.URL))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("plain\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+	assert.Len(t, r.get(), 3)
+}
@@ -2,9 +2,11 @@ package console
 
 import (
 	"fmt"
-	"go.uber.org/zap/buffer"
 	"reflect"
+	"strconv"
 	"strings"
+
+	"go.uber.org/zap/buffer"
 )
 
 const (
@@ -52,6 +54,68 @@ const (
 	ansiBold        = 1
 )
 
+const (
+	// Dim, Italic and Underline flags for ColorScheme.
+	Dim           uint16 = 1<<bitsDim | NoColor
+	Italic        uint16 = 1<<bitsItalic | NoColor
+	Underline     uint16 = 1<<bitsUnderline | NoColor
+	bitsDim              = 9
+	bitsItalic           = 10
+	bitsUnderline        = 11
+)
+
+// Color is a ColorScheme color in its low 16 bits, or a 256 colors or
+// truecolor foreground and background of Color256, RGB and Background.
+// A ColorScheme color converts with Color(Red|Bold).
+type Color uint64
+
+// The foreground and background values of the 256 colors and truecolors,
+// their kind is in place of the ColorScheme color.
+const (
+	kind256 = 9
+	kindRGB = 10
+
+	bitsForegroundValue = 16
+	bitsBackgroundValue = 40
+	maskValue           = 1<<24 - 1
+)
+
+// Color256 returns the foreground color of index n of the 256 colors palette.
+func Color256(n uint8) Color {
+	return Color(n)<<bitsForegroundValue | kind256 | Color(NoColor)
+}
+
+// Background256 returns the background color of index n of the 256 colors palette.
+func Background256(n uint8) Color {
+	return Color256(n).Background()
+}
+
+// RGB returns the truecolor foreground color.
+func RGB(r, g, b uint8) Color {
+	return (Color(r)<<16|Color(g)<<8|Color(b))<<bitsForegroundValue | kindRGB | Color(NoColor)
+}
+
+// BackgroundRGB returns the truecolor background color.
+func BackgroundRGB(r, g, b uint8) Color {
+	return RGB(r, g, b).Background()
+}
+
+// Background returns the foreground color of c as a background color.
+func (c Color) Background() Color {
+	return c&maskForeground<<bitsBackground |
+		c>>bitsForegroundValue&maskValue<<bitsBackgroundValue |
+		Color(NoColor)
+}
+
+// basic returns c as a ColorScheme color, false for the 256 colors and
+// truecolors.
+func (c Color) basic() (uint16, bool) {
+	if c>>16 != 0 || c&maskForeground > 8<<bitsForeground || c&maskBackground > 8<<bitsBackground {
+		return 0, false
+	}
+	return uint16(c), true
+}
+
 // To use with SetColorScheme.
 type ColorScheme struct {
 	Bool            uint16
@@ -78,6 +142,43 @@ type ColorScheme struct {
 	DebugLevel  uint16
 }
 
+// ColorTheme is a ColorScheme of the 256 colors and truecolors.
+type ColorTheme struct {
+	Bool            Color
+	Integer         Color
+	Float           Color
+	String          Color
+	StringQuotation Color
+	EscapedChar     Color
+	FieldName       Color
+	PointerAddress  Color
+	Nil             Color
+	Time            Color
+	StructName      Color
+	ObjectLength    Color
+
+	LogNaming   Color
+	Timestamp   Color
+	InfoLevel   Color
+	WarnLevel   Color
+	ErrorLevel  Color
+	FatalLevel  Color
+	PanicLevel  Color
+	DPanicLevel Color
+	DebugLevel  Color
+}
+
+// ColorTheme returns the colors of the scheme as a ColorTheme.
+func (cs ColorScheme) ColorTheme() ColorTheme {
+	var theme ColorTheme
+	from := reflect.ValueOf(cs)
+	to := reflect.ValueOf(&theme).Elem()
+	for i := 0; i < from.NumField(); i++ {
+		to.Field(i).SetUint(from.Field(i).Uint())
+	}
+	return theme
+}
+
 var (
 	defaultScheme = ColorScheme{
 		Bool:            Cyan | Bold,
@@ -104,6 +205,80 @@ var (
 		DebugLevel:  Blue,
 	}
 
+	// lightTheme keeps to the darker colors of the 256 colors palette,
+	// readable on a light background.
+	lightTheme = ColorTheme{
+		Bool:            Color256(30),
+		Integer:         Color256(25),
+		Float:           Color256(90),
+		String:          Color256(124),
+		StringQuotation: Color256(124) | Color(Bold),
+		EscapedChar:     Color256(90) | Color(Bold),
+		FieldName:       Color256(130),
+		PointerAddress:  Color256(25),
+		Nil:             Color256(30) | Color(Italic),
+		Time:            Color256(25),
+		StructName:      Color256(28),
+		ObjectLength:    Color256(25),
+
+		LogNaming:   Color256(236) | Background256(254),
+		Timestamp:   Color256(242),
+		InfoLevel:   Color256(28),
+		WarnLevel:   Color256(166) | Color(Bold),
+		ErrorLevel:  Color256(160),
+		FatalLevel:  Color256(160) | Color(Bold),
+		PanicLevel:  Color256(160) | Color(Bold),
+		DPanicLevel: Color256(231) | Color(Bold) | Background256(160),
+		DebugLevel:  Color256(61),
+	}
+
+	// solarizedTheme uses the truecolor accents of the Solarized palette.
+	solarizedTheme = ColorTheme{
+		Bool:            solarizedCyan,
+		Integer:         solarizedBlue,
+		Float:           solarizedViolet,
+		String:          solarizedOrange,
+		StringQuotation: solarizedOrange | Color(Bold),
+		EscapedChar:     solarizedMagenta,
+		FieldName:       solarizedYellow,
+		PointerAddress:  solarizedBlue,
+		Nil:             solarizedCyan | Color(Italic),
+		Time:            solarizedBlue,
+		StructName:      solarizedGreen,
+		ObjectLength:    solarizedBase01,
+
+		LogNaming:   solarizedBase1 | solarizedBase02.Background(),
+		Timestamp:   solarizedBase01,
+		InfoLevel:   solarizedGreen,
+		WarnLevel:   solarizedYellow | Color(Bold),
+		ErrorLevel:  solarizedRed,
+		FatalLevel:  solarizedRed | Color(Bold),
+		PanicLevel:  solarizedRed | Color(Bold),
+		DPanicLevel: solarizedBase3 | Color(Bold) | solarizedRed.Background(),
+		DebugLevel:  solarizedViolet,
+	}
+
+	solarizedBase02  = RGB(0x07, 0x36, 0x42)
+	solarizedBase01  = RGB(0x58, 0x6e, 0x75)
+	solarizedBase1   = RGB(0x93, 0xa1, 0xa1)
+	solarizedBase3   = RGB(0xfd, 0xf6, 0xe3)
+	solarizedYellow  = RGB(0xb5, 0x89, 0x00)
+	solarizedOrange  = RGB(0xcb, 0x4b, 0x16)
+	solarizedRed     = RGB(0xdc, 0x32, 0x2f)
+	solarizedMagenta = RGB(0xd3, 0x36, 0x82)
+	solarizedViolet  = RGB(0x6c, 0x71, 0xc4)
+	solarizedBlue    = RGB(0x26, 0x8b, 0xd2)
+	solarizedCyan    = RGB(0x2a, 0xa1, 0x98)
+	solarizedGreen   = RGB(0x85, 0x99, 0x00)
+
+	defaultTheme = defaultScheme.ColorTheme()
+
+	themes = map[string]ColorTheme{
+		"dark":      defaultTheme,
+		"light":     lightTheme,
+		"solarized": solarizedTheme,
+	}
+
 	colorMap = map[string]uint16{
 		"black":   Black,
 		"red":     Red,
@@ -115,95 +290,150 @@ var (
 		"white":   White,
 	}
 
-	colorBgMap = map[string]uint16{
-		"black":   BackgroundBlack,
-		"red":     BackgroundRed,
-		"green":   BackgroundGreen,
-		"yellow":  BackgroundYellow,
-		"blue":    BackgroundBlue,
-		"magenta": BackgroundMagenta,
-		"cyan":    BackgroundCyan,
-		"white":   BackgroundWhite,
+	attributeMap = map[string]uint16{
+		"b":         Bold,
+		"bold":      Bold,
+		"d":         Dim,
+		"dim":       Dim,
+		"i":         Italic,
+		"italic":    Italic,
+		"u":         Underline,
+		"underline": Underline,
 	}
 )
 
-func getColor(color string) uint16 {
+// Theme returns the built-in color theme of the name: dark, light or solarized.
+func Theme(name string) (ColorTheme, bool) {
+	scheme, ok := themes[strings.ToLower(name)]
+	return scheme, ok
+}
+
+// ParseColor parses a foreground color, a basic color name, a 256 colors
+// index or #rrggbb, followed by +attributes and a background color after
+// a comma, e.g. "blue+b", "208+b+u,236" or "#268bd2+i,#073642".
+func ParseColor(s string) (Color, error) {
+	colors := strings.SplitN(s, ",", 2)
 
-	val, ok := colorMap[strings.ToLower(color)]
-	if ok {
-		return val
+	parts := strings.Split(colors[0], "+")
+	color, err := parseSingleColor(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	for _, attr := range parts[1:] {
+		a, ok := attributeMap[strings.ToLower(strings.TrimSpace(attr))]
+		if !ok {
+			return 0, fmt.Errorf("unknown color attribute %q", attr)
+		}
+		color |= Color(a)
 	}
-	return 0
-}
 
-func getBgColor(color string) uint16 {
+	if len(colors) > 1 {
+		bg, err := parseSingleColor(colors[1])
+		if err != nil {
+			return 0, err
+		}
+		color |= bg.Background()
+	}
+	return color, nil
+}
 
-	val, ok := colorBgMap[strings.ToLower(color)]
-	if ok {
-		return val
+func parseSingleColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case len(s) == 0:
+		return 0, nil
+	case s[0] == '#':
+		rgb, err := strconv.ParseUint(s[1:], 16, 24)
+		if err != nil || len(s) != 7 {
+			return 0, fmt.Errorf("invalid truecolor %q, want #rrggbb", s)
+		}
+		return RGB(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+	case s[0] >= '0' && s[0] <= '9':
+		n, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid color index %q, want 0-255", s)
+		}
+		return Color256(uint8(n)), nil
+	}
+	if c, ok := colorMap[s]; ok {
+		return Color(c), nil
 	}
-	return 0
+	return 0, fmt.Errorf("unknown color %q", s)
 }
 
-func (cs *ColorScheme) fixColors() {
-	typ := reflect.Indirect(reflect.ValueOf(cs))
-	defaultType := reflect.ValueOf(defaultScheme)
+func (ct *ColorTheme) fixColors(base ColorTheme) {
+	typ := reflect.Indirect(reflect.ValueOf(ct))
+	baseType := reflect.ValueOf(base)
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
 		if field.Uint() == 0 {
-			field.SetUint(defaultType.Field(i).Uint())
+			field.SetUint(baseType.Field(i).Uint())
 		}
 	}
 }
 
-func colorizeText(text string, color uint16) string {
-	foreground := color & maskForeground >> bitsForeground
-	background := color & maskBackground >> bitsBackground
-	bold := color & maskBold
-
-	if foreground == 0 && background == 0 && bold == 0 {
-		return text
-	}
-
-	modBold := ""
-	modForeground := ""
-	modBackground := ""
-
-	if bold > 0 {
-		modBold = "\033[1m"
-	}
-	if foreground > 0 {
-		modForeground = fmt.Sprintf("\033[%dm", foreground+ansiForegroundOffset)
-	}
-	if background > 0 {
-		modBackground = fmt.Sprintf("\033[%dm", background+ansiBackgroundOffset)
+// scheme returns the theme as a ColorScheme, the 256 colors and
+// truecolors are the ones of base.
+func (ct ColorTheme) scheme(base ColorScheme) ColorScheme {
+	scheme := base
+	from := reflect.ValueOf(ct)
+	to := reflect.ValueOf(&scheme).Elem()
+	for i := 0; i < from.NumField(); i++ {
+		if c, ok := Color(from.Field(i).Uint()).basic(); ok {
+			to.Field(i).SetUint(uint64(c))
+		}
 	}
-
-	return fmt.Sprintf("%s%s%s%s\033[0m", modForeground, modBackground, modBold, text)
+	return scheme
 }
 
-func colorizeTextW(in *buffer.Buffer, text string, color uint16) {
+func colorizeTextW(in *buffer.Buffer, text string, color Color) {
 
-	if color&maskForeground>>bitsForeground == 0 &&
-		color&maskBackground>>bitsBackground == 0 &&
-		color&maskBold == 0 {
+	if color&^Color(NoColor) == 0 {
 		in.AppendString(text)
+		return
 	}
 
-	if foreground := color & maskForeground >> bitsForeground; foreground > 0 {
-		in.AppendString("\u001B[")
-		in.AppendUint(uint64(foreground + ansiForegroundOffset))
-		in.AppendString("m")
-
+	in.AppendString("\033[")
+	sep := false
+	appendParam := func(p uint64) {
+		if sep {
+			in.AppendByte(';')
+		}
+		in.AppendUint(p)
+		sep = true
+	}
+	appendColor := func(kind, value Color, offset uint64) {
+		switch {
+		case kind == 0:
+		case kind <= 8:
+			appendParam(uint64(kind) + offset)
+		case kind == kind256:
+			appendParam(offset + 9)
+			appendParam(5)
+			appendParam(uint64(value))
+		case kind == kindRGB:
+			appendParam(offset + 9)
+			appendParam(2)
+			appendParam(uint64(value >> 16 & 0xff))
+			appendParam(uint64(value >> 8 & 0xff))
+			appendParam(uint64(value & 0xff))
+		}
+	}
+	appendColor(color&maskForeground>>bitsForeground, color>>bitsForegroundValue&maskValue, ansiForegroundOffset)
+	appendColor(color&maskBackground>>bitsBackground, color>>bitsBackgroundValue&maskValue, ansiBackgroundOffset)
+	if color&maskBold > 0 {
+		appendParam(ansiBold)
+	}
+	if color>>bitsDim&1 > 0 {
+		appendParam(2)
 	}
-	if background := color & maskBackground >> bitsBackground; background > 0 {
-		in.AppendString("\033[")
-		in.AppendUint(uint64(background + ansiBackgroundOffset))
-		in.AppendString("m")
+	if color>>bitsItalic&1 > 0 {
+		appendParam(3)
 	}
-	if (color & maskBold) > 0 {
-		in.AppendString("\033[1m")
+	if color>>bitsUnderline&1 > 0 {
+		appendParam(4)
 	}
+	in.AppendByte('m')
 
 	in.AppendString(text)
 
@@ -19,7 +19,7 @@ var poolColoredEncoder = sync.Pool{
 	},
 }
 
-func getColoredEncoder(lvlColor uint16, scheme ColorScheme, disableColor bool) *coloredEncoder {
+func getColoredEncoder(lvlColor Color, scheme ColorTheme, disableColor bool) *coloredEncoder {
 	enc := poolColoredEncoder.Get().(*coloredEncoder)
 	enc.buf = bufferpool.Get()
 	enc.scheme = scheme
@@ -28,7 +28,7 @@ func getColoredEncoder(lvlColor uint16, scheme ColorScheme, disableColor bool) *
 	return enc
 }
 
-func getLevelColor(level zapcore.Level, scheme ColorScheme) uint16 {
+func getLevelColor(level zapcore.Level, scheme ColorTheme) Color {
 	switch level {
 	case zapcore.DebugLevel:
 		return scheme.DebugLevel
@@ -50,9 +50,9 @@ func getLevelColor(level zapcore.Level, scheme ColorScheme) uint16 {
 }
 
 func putColoredEncoder(enc *coloredEncoder) {
-	enc.scheme = defaultScheme
+	enc.scheme = defaultTheme
 	enc.buf.Free()
-	enc.entLevelColor = NoColor
+	enc.entLevelColor = Color(NoColor)
 	enc.disableColor = false
 	enc.EncodeDuration = nil
 	enc.EncodeTime = nil
@@ -62,9 +62,9 @@ func putColoredEncoder(enc *coloredEncoder) {
 type coloredEncoder struct {
 	buf          *buffer.Buffer
 	disableColor bool
-	scheme       ColorScheme
+	scheme       ColorTheme
 
-	entLevelColor uint16
+	entLevelColor Color
 
 	EncodeDuration zapcore.DurationEncoder
 	EncodeTime     zapcore.TimeEncoder
@@ -76,7 +76,7 @@ func (e *coloredEncoder) addKey(key string) {
 	e.buf.AppendByte('=')
 }
 
-func (e *coloredEncoder) appendColoredString(val string, color uint16) {
+func (e *coloredEncoder) appendColoredString(val string, color Color) {
 
 	if e.disableColor {
 		e.buf.AppendString(val)
@@ -0,0 +1,196 @@
+package console
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/khorevaa/logos/appender"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   Color
+		hasErr bool
+	}{
+		{"", 0, false},
+		{"blue+b", Color(Blue | Bold), false},
+		{"red, white", Color(Red | BackgroundWhite), false},
+		{"208+b+u,236", Color256(208) | Color(Bold|Underline) | Background256(236), false},
+		{"#268BD2+italic+dim,#073642", RGB(0x26, 0x8b, 0xd2) | Color(Italic|Dim) | BackgroundRGB(0x07, 0x36, 0x42), false},
+		{",blue", Color(BackgroundBlue), false},
+		{"black", Color(Black), false},
+		{"0", Color256(0), false},
+		{"256", 0, true},
+		{"#12345", 0, true},
+		{"#gggggg", 0, true},
+		{"pink", 0, true},
+		{"red+blink", 0, true},
+	}
+
+	for _, c := range tests {
+		got, err := ParseColor(c.in)
+		assert.Equal(t, c.hasErr, err != nil, c.in)
+		assert.Equal(t, c.want, got, c.in)
+	}
+}
+
+func TestColorizeTextW(t *testing.T) {
+	tests := []struct {
+		color Color
+		want  string
+	}{
+		{0, "x"},
+		{Color(NoColor), "x"},
+		{Color(Red), "\033[31mx\033[0m"},
+		{Color(Black | Bold | BackgroundWhite), "\033[30;47;1mx\033[0m"},
+		{Color256(208) | Background256(236) | Color(Underline), "\033[38;5;208;48;5;236;4mx\033[0m"},
+		{RGB(1, 2, 3) | BackgroundRGB(4, 5, 6) | Color(Dim|Italic), "\033[38;2;1;2;3;48;2;4;5;6;2;3mx\033[0m"},
+	}
+
+	for _, c := range tests {
+		buf := bufferpool.Get()
+		colorizeTextW(buf, "x", c.color)
+		assert.Equal(t, c.want, buf.String())
+		buf.Free()
+	}
+}
+
+func TestNewEncoder_theme(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		hasErr bool
+	}{
+		{"case1", `
+console:
+ theme: solarized`, false},
+		{"case2", `
+console:
+ theme: neon`, true},
+		{"case3", `
+console:
+ theme: light
+ color_scheme:
+  info_level: "#00ff00+b"
+  field_name: 244+i`, false},
+		{"case4", `
+console:
+ color_scheme:
+  info_level: "#00ff0"`, true},
+		{"case5", `
+console:
+ color_scheme:
+  info_level: blue+b`, false},
+	}
+
+	for _, c := range tests {
+		cfg, err := common.NewConfigFrom(c.config)
+		assert.Nil(t, err, c.name)
+		ec := appender.EncoderConfig{}
+		if err = cfg.Unpack(&ec); err == nil {
+			_, err = appender.CreateEncoder(ec)
+		}
+		assert.Equal(t, c.hasErr, err != nil, c.name)
+	}
+}
+
+func TestColorSchemaConfig_ParseWith(t *testing.T) {
+	scheme := ColorSchemaConfig{
+		InfoLevel: "#00ff00+b",
+		FieldName: "244+i",
+	}.ParseWith(lightTheme)
+
+	assert.Equal(t, RGB(0, 0xff, 0)|Color(Bold), scheme.InfoLevel)
+	assert.Equal(t, Color256(244)|Color(Italic), scheme.FieldName)
+	assert.Equal(t, lightTheme.WarnLevel, scheme.WarnLevel)
+	assert.Equal(t, lightTheme.String, scheme.String)
+	assert.Equal(t, scheme.InfoLevel, getLevelColor(zapcore.InfoLevel, scheme))
+}
+
+func TestColorSchemaConfig_Parse(t *testing.T) {
+	scheme := ColorSchemaConfig{
+		InfoLevel: "blue+b,white",
+		WarnLevel: "208",
+		FieldName: "red+u",
+	}.Parse()
+
+	assert.Equal(t, Blue|Bold|BackgroundWhite, scheme.InfoLevel)
+	assert.Equal(t, defaultScheme.WarnLevel, scheme.WarnLevel)
+	assert.Equal(t, Red|Underline, scheme.FieldName)
+	assert.Equal(t, defaultScheme.String, scheme.String)
+}
+
+func TestColorScheme_ColorTheme(t *testing.T) {
+	theme := ColorScheme{InfoLevel: Green | Bold, FieldName: Yellow}.ColorTheme()
+
+	assert.Equal(t, Color(Green|Bold), theme.InfoLevel)
+	assert.Equal(t, Color(Yellow), theme.FieldName)
+	assert.Equal(t, Color(0), theme.String)
+
+	buf := bufferpool.Get()
+	defer buf.Free()
+	enc := NewEncoder(EncoderConfig{Schema: defaultScheme})
+	enc.colorizeText(buf, "x", getLevelColor(zapcore.InfoLevel, enc.theme))
+	assert.Equal(t, "\033[32mx\033[0m", buf.String())
+}
+
+func TestCreateAppender_forceColors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out")
+	f, err := os.Create(name)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer f.Close()
+
+	// the console writer targets stdout, a file here
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+
+	tests := []struct {
+		name    string
+		config  string
+		colored bool
+	}{
+		{"encoder force_colors", `
+target: stdout
+encoder:
+ console:
+  force_colors: true`, true},
+		{"writer no_color", `
+target: stdout
+no_color: true
+encoder:
+ console:
+  force_colors: true`, false},
+		{"not forced", `
+target: stdout
+encoder:
+ console:`, false},
+	}
+
+	for _, c := range tests {
+		assert.NoError(t, f.Truncate(0))
+		_, err = f.Seek(0, 0)
+		assert.NoError(t, err)
+
+		a, err := appender.CreateAppender("console", common.MustNewConfigFrom(c.config))
+		if !assert.NoError(t, err, c.name) {
+			continue
+		}
+		core := a.NewCore(zapcore.DebugLevel)
+		assert.NoError(t, core.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "hello"}, nil), c.name)
+
+		data, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "hello", c.name)
+		assert.Equal(t, c.colored, strings.Contains(string(data), "\033["), c.name)
+	}
+}
@@ -1,6 +1,9 @@
 package console
 
-import "strings"
+import (
+	"fmt"
+	"reflect"
+)
 
 // Schema is the color schema for the default log parts/levels
 type ColorSchemaConfig struct {
@@ -13,11 +16,27 @@ type ColorSchemaConfig struct {
 	PanicLevel  string `logos-config:"panic_level"`
 	DPanicLevel string `logos-config:"dpanic_level"`
 	DebugLevel  string `logos-config:"debug_level"`
+
+	FieldName string `logos-config:"field_name"`
+	String    string `logos-config:"string"`
+	Integer   string `logos-config:"integer"`
+	Float     string `logos-config:"float"`
+	Bool      string `logos-config:"bool"`
+	Nil       string `logos-config:"nil"`
+	Time      string `logos-config:"time"`
 }
 
+// Parse returns the scheme of the config, the colors not set and the 256
+// colors and truecolors are the default ones, see ParseWith.
 func (c ColorSchemaConfig) Parse() ColorScheme {
+	return c.ParseWith(defaultTheme).scheme(defaultScheme)
+}
 
-	scheme := ColorScheme{}
+// ParseWith returns the theme of the config, the colors not set are the
+// ones of base.
+func (c ColorSchemaConfig) ParseWith(base ColorTheme) ColorTheme {
+
+	scheme := ColorTheme{}
 
 	scheme.Timestamp = parseFieldColor(c.Timestamp)
 	scheme.LogNaming = parseFieldColor(c.Naming)
@@ -29,46 +48,47 @@ func (c ColorSchemaConfig) Parse() ColorScheme {
 	scheme.DPanicLevel = parseFieldColor(c.DPanicLevel)
 	scheme.DebugLevel = parseFieldColor(c.DebugLevel)
 
-	scheme.fixColors()
+	scheme.FieldName = parseFieldColor(c.FieldName)
+	scheme.String = parseFieldColor(c.String)
+	scheme.StringQuotation = parseFieldColor(c.String)
+	scheme.Integer = parseFieldColor(c.Integer)
+	scheme.Float = parseFieldColor(c.Float)
+	scheme.Bool = parseFieldColor(c.Bool)
+	scheme.Nil = parseFieldColor(c.Nil)
+	scheme.Time = parseFieldColor(c.Time)
+
+	scheme.fixColors(base)
 	return scheme
 }
 
-func parseFieldColor(colorString string) uint16 {
-
-	colors := strings.Split(colorString, ",")
-
-	if len(colors) == 0 {
-		return 0
-	}
-
-	color := colors[0]
-	bold := uint16(0)
-	bgColor := ""
-
-	if arr := strings.Split(color, "+"); len(arr) > 1 {
-		color = strings.TrimSpace(arr[0])
-
-		if strings.TrimSpace(arr[1]) == "b" {
-			bold = Bold
+// Validate checks that all colors of the config parse.
+func (c ColorSchemaConfig) Validate() error {
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		if _, err := ParseColor(v.Field(i).String()); err != nil {
+			return fmt.Errorf("color_scheme %s: %w", v.Type().Field(i).Tag.Get("logos-config"), err)
 		}
-
-	}
-	if len(colors) > 1 {
-		bgColor = strings.TrimSpace(colors[1])
 	}
+	return nil
+}
 
-	return getColor(color) | bold | getBgColor(bgColor)
-
+// parseFieldColor parses the color, the invalid colors are not set.
+func parseFieldColor(colorString string) Color {
+	color, _ := ParseColor(colorString)
+	return color
 }
 
 // Config is used to pass encoding parameters to New.
 type Config struct {
 
-	// color schema for messages
+	// built-in color scheme: dark (default), light or solarized
+	Theme string `logos-config:"theme"`
+	// color schema for messages, overrides the colors of the theme
 	ColorSchema *ColorSchemaConfig `logos-config:"color_scheme"`
 	// no colors
 	DisableColors bool `logos-config:"disable_colors"`
-	// no check for TTY terminal
+	// colors even if neither stdout nor stderr is a terminal or the
+	// environment sets NO_COLOR, FORCE_COLOR=0 or TERM=dumb
 	ForceColors bool `logos-config:"force_colors"`
 	// false -> name passed, true -> github.com/khorevaa/logos
 	DisableNaming bool `logos-config:"disable_naming"`
@@ -109,4 +129,7 @@ type EncoderConfig struct {
 	TimestampFormat string
 	// color schema for messages
 	Schema ColorScheme
+	// color theme for messages with 256 colors and truecolors, replaces
+	// Schema if set
+	Theme *ColorTheme
 }
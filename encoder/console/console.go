@@ -2,6 +2,7 @@ package console
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/khorevaa/logos/appender"
@@ -29,7 +30,7 @@ func init() {
 		}
 
 		encoderConfig := EncoderConfig{
-			DisableColors:            config.DisableColors,
+			DisableColors:            config.DisableColors || !colorsEnabled(config.ForceColors),
 			ForceColors:              config.ForceColors,
 			DisableNaming:            config.DisableNaming,
 			DisableTimestamp:         config.DisableTimestamp,
@@ -41,20 +42,39 @@ func init() {
 		}
 
 		encoderConfig.Schema = defaultScheme
+		theme := defaultTheme
+		if len(config.Theme) > 0 {
+			var ok bool
+			if theme, ok = Theme(config.Theme); !ok {
+				return nil, fmt.Errorf("unknown console theme %q", config.Theme)
+			}
+		}
 
 		if config.ColorSchema != nil {
-			encoderConfig.Schema = config.ColorSchema.Parse()
+			theme = config.ColorSchema.ParseWith(theme)
 		}
+		encoderConfig.Theme = &theme
 		en := NewEncoder(encoderConfig)
 		return en, nil
 
 	})
 }
 
+// colorsEnabled reports whether a console is likely to show the colors,
+// the writers remove them from the outputs that are not terminals.
+func colorsEnabled(force bool) bool {
+	return common.ColorsEnabled(force, os.Stdout) || common.ColorsEnabled(force, os.Stderr)
+}
+
 // NewEncoder initializes a a bol.com tailored Encoder
 func NewEncoder(cfg EncoderConfig) *Encoder {
+	theme := cfg.Schema.ColorTheme()
+	if cfg.Theme != nil {
+		theme = *cfg.Theme
+	}
 	return &Encoder{
 		buf:           bufferpool.Get(),
+		theme:         theme,
 		EncoderConfig: cfg,
 	}
 }
@@ -62,10 +82,16 @@ func NewEncoder(cfg EncoderConfig) *Encoder {
 // Encoder is a bol.com tailored zap encoder for
 // writing human readable logs to the console
 type Encoder struct {
-	buf *buffer.Buffer
+	buf   *buffer.Buffer
+	theme ColorTheme
 	EncoderConfig
 }
 
+// ColorsForced reports whether the encoder writes colors to any output.
+func (e *Encoder) ColorsForced() bool {
+	return e.ForceColors && !e.DisableColors
+}
+
 // Clone implements the Clone method of the zapcore Encoder interface
 func (e *Encoder) Clone() zapcore.Encoder {
 	clone := e.clone()
@@ -76,6 +102,7 @@ func (e *Encoder) Clone() zapcore.Encoder {
 func (e *Encoder) clone() *Encoder {
 	clone := get()
 	clone.EncoderConfig = e.EncoderConfig
+	clone.theme = e.theme
 	clone.buf = bufferpool.Get()
 	return clone
 }
@@ -85,7 +112,7 @@ func (e *Encoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffe
 
 	line := bufferpool.Get()
 
-	lvlColor := getLevelColor(ent.Level, e.Schema)
+	lvlColor := getLevelColor(ent.Level, e.theme)
 
 	e.appendTimeInfo(line, ent)
 
@@ -95,14 +122,14 @@ func (e *Encoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffe
 	if !e.DisableNaming && len(ent.LoggerName) > 0 {
 
 		e.addSeparatorIfNecessary(line)
-		e.colorizeText(line, ent.LoggerName, e.Schema.LogNaming)
+		e.colorizeText(line, ent.LoggerName, e.theme.LogNaming)
 
 	}
 
 	if ent.Caller.Defined {
 
 		e.addSeparatorIfNecessary(line)
-		e.colorizeText(line, ent.Caller.TrimmedPath(), e.Schema.Nil)
+		e.colorizeText(line, ent.Caller.TrimmedPath(), e.theme.Nil)
 
 	}
 
@@ -140,14 +167,14 @@ func (e *Encoder) appendTimeInfo(buf *buffer.Buffer, entry zapcore.Entry) {
 
 		if e.UseTimePassedAsTimestamp {
 
-			e.colorizeText(buf, fmt.Sprintf("[%04d]", int(entry.Time.Sub(baseTimestamp)/time.Second)), e.Schema.Timestamp)
+			e.colorizeText(buf, fmt.Sprintf("[%04d]", int(entry.Time.Sub(baseTimestamp)/time.Second)), e.theme.Timestamp)
 
 		} else {
 
 			if e.TimestampFormat == "" {
-				e.colorizeText(buf, entry.Time.Format(defaultTimestampFormat), e.Schema.Timestamp)
+				e.colorizeText(buf, entry.Time.Format(defaultTimestampFormat), e.theme.Timestamp)
 			} else {
-				e.colorizeText(buf, entry.Time.Format(e.TimestampFormat), e.Schema.Timestamp)
+				e.colorizeText(buf, entry.Time.Format(e.TimestampFormat), e.theme.Timestamp)
 			}
 
 		}
@@ -157,13 +184,13 @@ func (e *Encoder) appendTimeInfo(buf *buffer.Buffer, entry zapcore.Entry) {
 
 }
 
-func (e *Encoder) writeContext(defColor uint16, out *buffer.Buffer, extra []zapcore.Field) {
+func (e *Encoder) writeContext(defColor Color, out *buffer.Buffer, extra []zapcore.Field) {
 
 	if len(extra) == 0 {
 		return
 	}
 
-	enc := getColoredEncoder(defColor, e.Schema, e.DisableColors)
+	enc := getColoredEncoder(defColor, e.theme, e.DisableColors)
 	defer putColoredEncoder(enc)
 
 	addFields(enc, extra)
@@ -180,7 +207,7 @@ func (e *Encoder) addSeparatorIfNecessary(line *buffer.Buffer) {
 	}
 }
 
-func (e *Encoder) colorizeText(in *buffer.Buffer, text string, color uint16) {
+func (e *Encoder) colorizeText(in *buffer.Buffer, text string, color Color) {
 
 	if e.DisableColors {
 		in.AppendString(text)
@@ -196,7 +223,7 @@ func (e *Encoder) addKey(key string) {
 	if e.DisableColors {
 		e.buf.AppendString(key)
 	} else {
-		colorizeTextW(e.buf, key, e.Schema.FieldName)
+		colorizeTextW(e.buf, key, e.theme.FieldName)
 	}
 	e.buf.AppendByte('=')
 
@@ -3,5 +3,8 @@ package logos
 import "errors"
 
 var (
-	ErrEnvConfigNotSet = errors.New("environment variable 'LOGOS_CONFIG' is not set")
+	ErrEnvConfigNotSet    = errors.New("environment variable 'LOGOS_CONFIG' is not set")
+	ErrAppenderNotFound   = errors.New("appender not found")
+	ErrDumpNotSupported   = errors.New("appender does not support dump")
+	ErrRotateNotSupported = errors.New("appender does not support rotate")
 )
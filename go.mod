@@ -4,9 +4,11 @@ go 1.15
 
 require (
 	github.com/elastic/go-ucfg v0.8.3
+	github.com/golang/snappy v0.0.4
 	github.com/mattn/go-colorable v0.1.8
+	github.com/mattn/go-isatty v0.0.12
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/stretchr/testify v1.6.1
 	go.uber.org/zap v1.16.0
-	gopkg.in/natefinch/lumberjack.v2 v2.0.0
-
+	golang.org/x/sys v0.0.0-20200223170610-d5e6a3e2c0ae
 )
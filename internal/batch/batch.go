@@ -0,0 +1,199 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config is the batching section shared by the buffering appenders.
+type Config struct {
+	// Size is the maximum number of items in a batch.
+	Size int `logos-config:"size" logos-validate:"min=1"`
+	// Bytes is the maximum size of a batch, 0 means no limit.
+	Bytes int `logos-config:"bytes" logos-validate:"min=0"`
+	// Interval is the maximum time an item waits before it is sent.
+	Interval time.Duration `logos-config:"interval"`
+}
+
+var ErrClosed = errors.New("batcher is closed")
+
+// SendFunc sends a batch of items. Items are in the order they were added.
+type SendFunc func(items []interface{}) error
+
+// Batcher collects items and sends them in batches from a background
+// goroutine, when a batch is full, when Interval elapses or on Flush.
+type Batcher struct {
+	config  Config
+	send    SendFunc
+	onError func(error)
+
+	mu     sync.Mutex
+	items  []interface{}
+	bytes  int
+	closed bool
+
+	batches chan request
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type request struct {
+	items  []interface{}
+	result chan error
+}
+
+// New starts a Batcher calling send for each batch.
+// Errors of batches sent in background are passed to onError, or printed
+// to stderr if onError is nil.
+func New(config Config, send SendFunc, onError func(error)) *Batcher {
+	if config.Size < 1 {
+		config.Size = 1
+	}
+	if onError == nil {
+		onError = func(err error) {
+			fmt.Fprintf(os.Stderr, "%v write error: %v\n", time.Now(), err)
+		}
+	}
+
+	b := &Batcher{
+		config:  config,
+		send:    send,
+		onError: onError,
+		batches: make(chan request, 1),
+		done:    make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.run()
+	if config.Interval > 0 {
+		b.wg.Add(1)
+		go b.tick()
+	}
+	return b
+}
+
+// Add queues an item of the given size in bytes. It blocks while the
+// previous batches are still being sent.
+func (b *Batcher) Add(item interface{}, size int) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	if b.config.Bytes > 0 && len(b.items) > 0 && b.bytes+size > b.config.Bytes {
+		b.enqueue(b.take(), nil)
+	}
+
+	b.items = append(b.items, item)
+	b.bytes += size
+
+	if len(b.items) >= b.config.Size || b.config.Bytes > 0 && b.bytes >= b.config.Bytes {
+		b.enqueue(b.take(), nil)
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// Flush sends the queued items and waits until all batches are sent.
+// It returns the error of sending the queued items.
+func (b *Batcher) Flush() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	result := make(chan error, 1)
+	b.enqueue(b.take(), result)
+	b.mu.Unlock()
+
+	return <-result
+}
+
+// Close flushes the queued items and stops the background goroutine.
+// The items added later are refused with ErrClosed.
+func (b *Batcher) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	result := make(chan error, 1)
+	b.enqueue(b.take(), result)
+	b.mu.Unlock()
+
+	err := <-result
+	close(b.done)
+	b.wg.Wait()
+	return err
+}
+
+// take returns the queued items, b.mu must be held.
+func (b *Batcher) take() []interface{} {
+	items := b.items
+	b.items = nil
+	b.bytes = 0
+	return items
+}
+
+// enqueue passes the items to the background goroutine, b.mu must be held
+// so batches keep their order.
+func (b *Batcher) enqueue(items []interface{}, result chan error) {
+	b.batches <- request{items, result}
+}
+
+func (b *Batcher) run() {
+	defer b.wg.Done()
+
+	for {
+		select {
+		case req := <-b.batches:
+			b.process(req)
+		case <-b.done:
+			for {
+				select {
+				case req := <-b.batches:
+					b.process(req)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// tick sends the queued items every Interval.
+func (b *Batcher) tick() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.mu.Lock()
+			if !b.closed && len(b.items) > 0 {
+				b.enqueue(b.take(), nil)
+			}
+			b.mu.Unlock()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *Batcher) process(req request) {
+	var err error
+	if len(req.items) > 0 {
+		err = b.send(req.items)
+	}
+	if req.result != nil {
+		req.result <- err
+	} else if err != nil {
+		b.onError(err)
+	}
+}
@@ -0,0 +1,116 @@
+package batch
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]interface{}
+}
+
+func (r *recorder) send(items []interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, items)
+	return nil
+}
+
+func (r *recorder) get() [][]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batches
+}
+
+func TestBatcher_size(t *testing.T) {
+	r := &recorder{}
+	b := New(Config{Size: 2}, r.send, nil)
+
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, b.Add(i, 1))
+	}
+	assert.NoError(t, b.Close())
+
+	assert.Equal(t, [][]interface{}{{1, 2}, {3, 4}, {5}}, r.get())
+	assert.Equal(t, ErrClosed, b.Add(6, 1))
+}
+
+func TestBatcher_bytes(t *testing.T) {
+	r := &recorder{}
+	b := New(Config{Size: 100, Bytes: 10}, r.send, nil)
+
+	assert.NoError(t, b.Add("a", 4))
+	assert.NoError(t, b.Add("b", 4))
+	assert.NoError(t, b.Add("c", 4))
+	assert.NoError(t, b.Add("d", 20))
+	assert.NoError(t, b.Flush())
+
+	assert.Equal(t, [][]interface{}{{"a", "b"}, {"c"}, {"d"}}, r.get())
+	assert.NoError(t, b.Close())
+}
+
+func TestBatcher_interval(t *testing.T) {
+	r := &recorder{}
+	b := New(Config{Size: 100, Interval: 10 * time.Millisecond}, r.send, nil)
+	defer b.Close()
+
+	assert.NoError(t, b.Add(1, 1))
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestBatcher_errors(t *testing.T) {
+	errSend := errors.New("send failed")
+	errs := make(chan error, 1)
+	b := New(Config{Size: 1}, func([]interface{}) error {
+		return errSend
+	}, func(err error) {
+		errs <- err
+	})
+
+	assert.NoError(t, b.Add(1, 1))
+	assert.Equal(t, errSend, <-errs)
+
+	assert.NoError(t, b.Flush())
+	assert.NoError(t, b.Close())
+}
+
+func TestBatcher_closeConcurrentAdd(t *testing.T) {
+	for run := 0; run < 50; run++ {
+		r := &recorder{}
+		b := New(Config{Size: 3}, r.send, nil)
+
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			accepted int
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					if b.Add(i*100+j, 1) == nil {
+						mu.Lock()
+						accepted++
+						mu.Unlock()
+					}
+				}
+			}(i)
+		}
+		assert.NoError(t, b.Close())
+		wg.Wait()
+
+		sent := 0
+		for _, batch := range r.get() {
+			sent += len(batch)
+		}
+		assert.Equal(t, accepted, sent)
+	}
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"time"
+)
+
+// Backoff computes exponentially growing delays between retries,
+// starting at Min and capped at Max. It is not safe for concurrent use.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	attempts int
+}
+
+// Next returns the delay before the next attempt.
+func (b *Backoff) Next() time.Duration {
+	d := b.Min
+	for i := 0; i < b.attempts && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	b.attempts++
+	return d
+}
+
+// Reset starts the sequence over after a successful attempt.
+func (b *Backoff) Reset() {
+	b.attempts = 0
+}
+
+// Attempts returns the number of failed attempts since the last Reset.
+func (b *Backoff) Attempts() int {
+	return b.attempts
+}
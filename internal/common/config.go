@@ -22,6 +22,7 @@ type ConfigNamespace struct {
 var configOpts = []ucfg.Option{
 	ucfg.PathSep("."),
 	ucfg.ResolveEnv,
+	ucfg.Resolve(resolveProcessVar),
 	ucfg.VarExp,
 	ucfg.StructTag("logos-config"),
 	ucfg.ValidatorTag("logos-validate"),
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateVerbs are the date verbs of ExpandDate, with the regular expression
+// matching their expansion: %Y year, %m month, %d day, %H hour, %M minute
+// and %j day of the year.
+var DateVerbs = map[byte]string{
+	'Y': `\d{4}`,
+	'm': `\d\d`,
+	'd': `\d\d`,
+	'H': `\d\d`,
+	'M': `\d\d`,
+	'j': `\d{3}`,
+}
+
+// HasDateVerbs reports whether s contains date verbs.
+func HasDateVerbs(s string) bool {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] == '%' {
+			if _, ok := DateVerbs[s[i+1]]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExpandDate replaces the date verbs of s with the date of t and %% with %,
+// the other verbs are kept.
+func ExpandDate(s string, t time.Time) string {
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '%' || i == len(s)-1 {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'Y':
+			sb.WriteString(strconv.Itoa(t.Year()))
+		case 'm':
+			writeDigits(&sb, int(t.Month()), 2)
+		case 'd':
+			writeDigits(&sb, t.Day(), 2)
+		case 'H':
+			writeDigits(&sb, t.Hour(), 2)
+		case 'M':
+			writeDigits(&sb, t.Minute(), 2)
+		case 'j':
+			writeDigits(&sb, t.YearDay(), 3)
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+func writeDigits(sb *strings.Builder, v, width int) {
+	s := strconv.Itoa(v)
+	for i := len(s); i < width; i++ {
+		sb.WriteByte('0')
+	}
+	sb.WriteString(s)
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldValues returns the values of the named fields formatted as strings.
+// Fields missing in fields are not set in the result.
+func FieldValues(fields []zapcore.Field, names ...string) map[string]string {
+	if len(names) == 0 || len(fields) == 0 {
+		return nil
+	}
+
+	wanted := MakeStringSet(names...)
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		if wanted.Has(fields[i].Key) {
+			fields[i].AddTo(enc)
+		}
+	}
+
+	values := make(map[string]string, len(enc.Fields))
+	for key, value := range enc.Fields {
+		values[key] = FormatValue(value)
+	}
+	return values
+}
+
+// FormatValue formats a value added to a zapcore.MapObjectEncoder.
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// FileMode is a permission configured as an octal number, e.g. 0640 or "0640".
+type FileMode os.FileMode
+
+func (m *FileMode) Unpack(v interface{}) error {
+	var mode uint64
+	switch v := v.(type) {
+	case int64:
+		mode = uint64(v)
+	case uint64:
+		mode = v
+	case string:
+		var err error
+		if mode, err = strconv.ParseUint(v, 8, 32); err != nil {
+			return fmt.Errorf("invalid file mode %q", v)
+		}
+	default:
+		return fmt.Errorf("invalid file mode %v", v)
+	}
+	if mode > 0777 {
+		return fmt.Errorf("invalid file mode %o", mode)
+	}
+	*m = FileMode(mode)
+	return nil
+}
+
+// Perm returns the mode as os.FileMode.
+func (m FileMode) Perm() os.FileMode {
+	return os.FileMode(m)
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"sync"
+)
+
+// MessageQueue is a bounded FIFO of messages, dropping the oldest
+// messages when it is full. It is safe for concurrent use.
+type MessageQueue struct {
+	mu      sync.Mutex
+	items   [][]byte
+	size    int
+	dropped int
+}
+
+func NewMessageQueue(size int) *MessageQueue {
+	return &MessageQueue{size: size}
+}
+
+// Push appends the message. It returns false if the queue has no room at all.
+func (q *MessageQueue) Push(msg []byte) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size == 0 {
+		q.dropped++
+		return false
+	}
+	if len(q.items) >= q.size {
+		q.items = q.items[1:]
+		q.dropped++
+	}
+	q.items = append(q.items, msg)
+	return true
+}
+
+// PushFront puts a message taken by Pop back, dropping the newest message
+// if the queue has been filled meanwhile.
+func (q *MessageQueue) PushFront(msg []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size == 0 {
+		q.dropped++
+		return
+	}
+	if len(q.items) >= q.size {
+		q.items = q.items[:len(q.items)-1]
+		q.dropped++
+	}
+	q.items = append([][]byte{msg}, q.items...)
+}
+
+// Pop removes and returns the oldest message.
+func (q *MessageQueue) Pop() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	msg := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	return msg, true
+}
+
+// Len returns the number of queued messages.
+func (q *MessageQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Dropped returns the number of messages dropped because the queue was full.
+func (q *MessageQueue) Dropped() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Reconnect paces the connection attempts of a writer with Backoff and
+// prints the first failure after a connection loss and the reconnect to
+// stderr, the same way zap reports write errors. It is not safe for
+// concurrent use.
+type Reconnect struct {
+	// Name identifies the connection in the reports, e.g. socket tcp://host:514.
+	Name    string
+	Backoff Backoff
+
+	nextAttempt time.Time
+}
+
+// Waiting reports whether the next attempt is still delayed.
+func (r *Reconnect) Waiting() bool {
+	return time.Now().Before(r.nextAttempt)
+}
+
+// Connected starts the backoff over, a reconnect is reported.
+func (r *Reconnect) Connected() {
+	if r.Backoff.Attempts() > 0 {
+		fmt.Fprintf(os.Stderr, "%v %s connected\n", time.Now(), r.Name)
+	}
+	r.Backoff.Reset()
+	r.nextAttempt = time.Time{}
+}
+
+// Failed delays the next attempt, the first failure since the last
+// connection is reported.
+func (r *Reconnect) Failed(err error) {
+	r.nextAttempt = time.Now().Add(r.Backoff.Next())
+	if r.Backoff.Attempts() == 1 {
+		fmt.Fprintf(os.Stderr, "%v %s disconnected: %v\n", time.Now(), r.Name, err)
+	}
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// ParseSignal returns the signal for a name like "SIGUSR1", "usr1" or "HUP".
+func ParseSignal(name string) (os.Signal, error) {
+	key := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(key, "SIG") {
+		key = "SIG" + key
+	}
+	if sig, ok := signals[key]; ok {
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unknown signal %q", name)
+}
+
+var commonSignals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
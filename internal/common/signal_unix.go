@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package common
+
+import (
+	"os"
+	"syscall"
+)
+
+var signals = func() map[string]os.Signal {
+	m := map[string]os.Signal{
+		"SIGUSR1": syscall.SIGUSR1,
+		"SIGUSR2": syscall.SIGUSR2,
+	}
+	for name, sig := range commonSignals {
+		m[name] = sig
+	}
+	return m
+}()
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package common
+
+import (
+	"os"
+)
+
+var signals = func() map[string]os.Signal {
+	m := map[string]os.Signal{}
+	for name, sig := range commonSignals {
+		m[name] = sig
+	}
+	return m
+}()
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ColorsEnabled reports whether colors are written to the files. force wins,
+// then the NO_COLOR, FORCE_COLOR and TERM=dumb conventions of the
+// environment, otherwise colors are enabled if all files are terminals.
+func ColorsEnabled(force bool, files ...*os.File) bool {
+	if force {
+		return true
+	}
+	if enabled, ok := envColors(); ok {
+		return enabled
+	}
+	for _, f := range files {
+		if !IsTerminal(f) {
+			return false
+		}
+	}
+	return len(files) > 0
+}
+
+// envColors returns the colors choice of the environment, ok is false when
+// the environment does not choose.
+func envColors() (enabled, ok bool) {
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false, true
+	}
+	if force, set := os.LookupEnv("FORCE_COLOR"); set {
+		switch strings.ToLower(force) {
+		case "0", "false", "no", "off":
+			return false, true
+		}
+		return true, true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false, true
+	}
+	return false, false
+}
+
+// IsTerminal reports whether the file is a terminal, including the Cygwin
+// and MSYS2 terminals on windows.
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fd := f.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
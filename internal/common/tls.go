@@ -0,0 +1,52 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// TLSConfig is the tls section shared by the network appenders.
+type TLSConfig struct {
+	Enabled            bool   `logos-config:"enabled"`
+	CAFile             string `logos-config:"ca_file"`
+	CertFile           string `logos-config:"cert_file"`
+	KeyFile            string `logos-config:"key_file"`
+	ServerName         string `logos-config:"server_name"`
+	InsecureSkipVerify bool   `logos-config:"insecure_skip_verify"`
+}
+
+// Build returns the tls.Config described by c, or nil if TLS is disabled.
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	if c == nil || !c.Enabled {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if len(c.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca_file")
+		}
+		config.RootCAs = pool
+	}
+
+	if len(c.CertFile) > 0 || len(c.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
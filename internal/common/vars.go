@@ -0,0 +1,84 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-ucfg"
+	"github.com/elastic/go-ucfg/parse"
+)
+
+// startTime is the process start used by ${start_date} and ${start_time}.
+var startTime = time.Now()
+
+var (
+	processVarsOnce sync.Once
+	processVars     map[string]string
+)
+
+// ProcessVars returns the process variables resolved in config strings
+// after the config itself and the environment:
+//
+//	${pid}         process id
+//	${hostname}    host name
+//	${exe}         executable name without extension
+//	${app}         LOGOS_APP_NAME or the executable name
+//	${start_date}  process start date, 2006-01-02
+//	${start_time}  process start time, 2006-01-02T15-04-05
+//
+// Several instances of a program can so write separate files from one
+// config, e.g. file_name: /var/log/${app}-${pid}.log. The values are
+// computed once, on the first call.
+func ProcessVars() map[string]string {
+	cached := loadProcessVars()
+	vars := make(map[string]string, len(cached))
+	for k, v := range cached {
+		vars[k] = v
+	}
+	return vars
+}
+
+// loadProcessVars returns the process variables shared by the callers.
+func loadProcessVars() map[string]string {
+	processVarsOnce.Do(func() {
+		processVars = newProcessVars()
+	})
+	return processVars
+}
+
+func newProcessVars() map[string]string {
+	hostname, _ := os.Hostname()
+	exe := executableName()
+	app := os.Getenv("LOGOS_APP_NAME")
+	if app == "" {
+		app = exe
+	}
+	return map[string]string{
+		"pid":        strconv.Itoa(os.Getpid()),
+		"hostname":   hostname,
+		"exe":        exe,
+		"app":        app,
+		"start_date": startTime.Format("2006-01-02"),
+		"start_time": startTime.Format("2006-01-02T15-04-05"),
+	}
+}
+
+func executableName() string {
+	path, err := os.Executable()
+	if err != nil {
+		path = os.Args[0]
+	}
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+func resolveProcessVar(name string) (string, parse.Config, error) {
+	if value, ok := loadProcessVars()[name]; ok && value != "" {
+		return value, parse.NoopConfig, nil
+	}
+	return "", parse.NoopConfig, ucfg.ErrMissing
+}
@@ -0,0 +1,27 @@
+// Package flock takes advisory exclusive locks on files, shared between
+// processes.
+package flock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrUnsupported is returned on platforms without file locks.
+var ErrUnsupported = errors.New("file locks are not supported on this platform")
+
+// Lock waits for the exclusive lock of the file.
+func Lock(f *os.File) error {
+	return lock(f)
+}
+
+// TryLock takes the exclusive lock of the file if it is free and reports
+// whether it did.
+func TryLock(f *os.File) (bool, error) {
+	return tryLock(f)
+}
+
+// Unlock releases the lock of the file.
+func Unlock(f *os.File) error {
+	return unlock(f)
+}
@@ -0,0 +1,20 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package flock
+
+import (
+	"os"
+)
+
+func lock(*os.File) error {
+	return ErrUnsupported
+}
+
+func tryLock(*os.File) (bool, error) {
+	return false, ErrUnsupported
+}
+
+func unlock(*os.File) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,36 @@
+package flock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.lock")
+	first, err := os.Create(name)
+	assert.NoError(t, err)
+	defer first.Close()
+	second, err := os.Open(name)
+	assert.NoError(t, err)
+	defer second.Close()
+
+	if err := Lock(first); err == ErrUnsupported {
+		t.Skip(err)
+	} else {
+		assert.NoError(t, err)
+	}
+
+	// the lock is held by the open file, not by the process
+	ok, err := TryLock(second)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, Unlock(first))
+	ok, err = TryLock(second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, Unlock(second))
+}
@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/khorevaa/logos/internal/common"
+)
+
+// Config is the http section shared by the appenders posting to a server.
+type Config struct {
+	URL     string            `logos-config:"url" logos-validate:"required"`
+	Headers map[string]string `logos-config:"headers"`
+	Timeout time.Duration     `logos-config:"timeout"`
+
+	// MaxRetries is the number of retries after network errors, 429 and
+	// 5xx responses.
+	MaxRetries int           `logos-config:"max_retries" logos-validate:"min=0"`
+	BackoffMin time.Duration `logos-config:"backoff_min"`
+	BackoffMax time.Duration `logos-config:"backoff_max"`
+
+	TLS common.TLSConfig `logos-config:"tls"`
+}
+
+var DefaultConfig = Config{
+	Timeout:    10 * time.Second,
+	MaxRetries: 3,
+	BackoffMin: 100 * time.Millisecond,
+	BackoffMax: 5 * time.Second,
+}
+
+// StatusError is returned for responses with a non 2xx status code.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response status %d: %s", e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+// Retryable reports whether the request may succeed if sent again.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client sends requests to the configured URL and retries them with
+// exponential backoff.
+type Client struct {
+	config Config
+	client *http.Client
+}
+
+func New(config Config) (*Client, error) {
+	if len(config.URL) == 0 {
+		return nil, errors.New("http url is required")
+	}
+
+	tlsConfig, err := config.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &Client{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
+// URL returns the configured URL.
+func (c *Client) URL() string {
+	return c.config.URL
+}
+
+// Post sends body to the configured URL.
+func (c *Client) Post(body []byte, header http.Header) ([]byte, error) {
+	return c.Do(http.MethodPost, c.config.URL, body, header)
+}
+
+// Do sends the request, retrying it while it fails with a retryable error.
+// It returns the body of the successful response.
+func (c *Client) Do(method, url string, body []byte, header http.Header) ([]byte, error) {
+	backoff := common.Backoff{Min: c.config.BackoffMin, Max: c.config.BackoffMax}
+
+	for {
+		resp, err := c.do(method, url, body, header)
+		if err == nil {
+			return resp, nil
+		}
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.Retryable() {
+			return nil, err
+		}
+		if backoff.Attempts() >= c.config.MaxRetries {
+			return nil, err
+		}
+		time.Sleep(backoff.Next())
+	}
+}
+
+func (c *Client) do(method, url string, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, values := range header {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: respBody}
+	}
+	return respBody, nil
+}
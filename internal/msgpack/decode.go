@@ -0,0 +1,199 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Ext is a decoded extension value.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// Time returns the time of an EventTime extension.
+func (e Ext) Time() (time.Time, bool) {
+	if e.Type != EventTimeType || len(e.Data) != 8 {
+		return time.Time{}, false
+	}
+	sec := binary.BigEndian.Uint32(e.Data[:4])
+	nsec := binary.BigEndian.Uint32(e.Data[4:])
+	return time.Unix(int64(sec), int64(nsec)), true
+}
+
+// Decoder reads values from a stream.
+type Decoder struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next value. Integers are decoded as int64 or uint64,
+// str as string, bin as []byte, arrays as []interface{}, maps with string
+// keys as map[string]interface{} and extensions as Ext.
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return uint64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(c - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return d.read(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(c - 0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(n)
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (c - 0xcc))
+	case 0xd0:
+		v, err := d.uint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.uint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.uint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.uint(8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(c - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(c - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.length(c - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("msgpack: invalid code %#x", c)
+}
+
+func (d *Decoder) byte() (byte, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:1]); err != nil {
+		return 0, err
+	}
+	return d.buf[0], nil
+}
+
+func (d *Decoder) uint(size int) (uint64, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:size]); err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range d.buf[:size] {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// length reads a length of 1, 2 or 4 bytes for the size classes 0, 1 and 2.
+func (d *Decoder) length(class byte) (int, error) {
+	v, err := d.uint(1 << class)
+	return int(v), err
+}
+
+func (d *Decoder) read(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	return b, err
+}
+
+func (d *Decoder) str(n int) (string, error) {
+	b, err := d.read(n)
+	return string(b), err
+}
+
+func (d *Decoder) ext(n int) (Ext, error) {
+	t, err := d.byte()
+	if err != nil {
+		return Ext{}, err
+	}
+	data, err := d.read(n)
+	return Ext{Type: int8(t), Data: data}, err
+}
+
+func (d *Decoder) decodeArray(n int) ([]interface{}, error) {
+	out := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (d *Decoder) decodeMap(n int) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case string:
+			out[k] = value
+		case []byte:
+			out[string(k)] = value
+		default:
+			out[fmt.Sprint(k)] = value
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,251 @@
+// Package msgpack is a minimal MessagePack encoder and decoder for the
+// forward protocol, so the appenders do not depend on a full library.
+package msgpack
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Buffer accumulates encoded values.
+type Buffer struct {
+	b []byte
+}
+
+// Bytes returns the encoded values.
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+// Reset empties the buffer keeping its memory.
+func (b *Buffer) Reset() {
+	b.b = b.b[:0]
+}
+
+// Len returns the number of encoded bytes.
+func (b *Buffer) Len() int {
+	return len(b.b)
+}
+
+// Raw appends already encoded values.
+func (b *Buffer) Raw(p []byte) {
+	b.b = append(b.b, p...)
+}
+
+func (b *Buffer) Nil() {
+	b.b = append(b.b, 0xc0)
+}
+
+func (b *Buffer) Bool(v bool) {
+	if v {
+		b.b = append(b.b, 0xc3)
+	} else {
+		b.b = append(b.b, 0xc2)
+	}
+}
+
+func (b *Buffer) Int(v int64) {
+	switch {
+	case v >= 0:
+		b.Uint(uint64(v))
+	case v >= -32:
+		b.b = append(b.b, byte(v))
+	case v >= math.MinInt8:
+		b.b = append(b.b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		b.b = append(b.b, 0xd1)
+		b.b = appendUint16(b.b, uint16(v))
+	case v >= math.MinInt32:
+		b.b = append(b.b, 0xd2)
+		b.b = appendUint32(b.b, uint32(v))
+	default:
+		b.b = append(b.b, 0xd3)
+		b.b = appendUint64(b.b, uint64(v))
+	}
+}
+
+func (b *Buffer) Uint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		b.b = append(b.b, byte(v))
+	case v <= math.MaxUint8:
+		b.b = append(b.b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		b.b = append(b.b, 0xcd)
+		b.b = appendUint16(b.b, uint16(v))
+	case v <= math.MaxUint32:
+		b.b = append(b.b, 0xce)
+		b.b = appendUint32(b.b, uint32(v))
+	default:
+		b.b = append(b.b, 0xcf)
+		b.b = appendUint64(b.b, v)
+	}
+}
+
+func (b *Buffer) Float64(v float64) {
+	b.b = append(b.b, 0xcb)
+	b.b = appendUint64(b.b, math.Float64bits(v))
+}
+
+func (b *Buffer) String(v string) {
+	n := len(v)
+	switch {
+	case n <= 31:
+		b.b = append(b.b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b.b = append(b.b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b.b = append(b.b, 0xda)
+		b.b = appendUint16(b.b, uint16(n))
+	default:
+		b.b = append(b.b, 0xdb)
+		b.b = appendUint32(b.b, uint32(n))
+	}
+	b.b = append(b.b, v...)
+}
+
+func (b *Buffer) Binary(v []byte) {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b.b = append(b.b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b.b = append(b.b, 0xc5)
+		b.b = appendUint16(b.b, uint16(n))
+	default:
+		b.b = append(b.b, 0xc6)
+		b.b = appendUint32(b.b, uint32(n))
+	}
+	b.b = append(b.b, v...)
+}
+
+// ArrayHeader starts an array of n values.
+func (b *Buffer) ArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		b.b = append(b.b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		b.b = append(b.b, 0xdc)
+		b.b = appendUint16(b.b, uint16(n))
+	default:
+		b.b = append(b.b, 0xdd)
+		b.b = appendUint32(b.b, uint32(n))
+	}
+}
+
+// MapHeader starts a map of n key value pairs.
+func (b *Buffer) MapHeader(n int) {
+	switch {
+	case n <= 15:
+		b.b = append(b.b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		b.b = append(b.b, 0xde)
+		b.b = appendUint16(b.b, uint16(n))
+	default:
+		b.b = append(b.b, 0xdf)
+		b.b = appendUint32(b.b, uint32(n))
+	}
+}
+
+// EventTimeType is the extension type of the forward protocol EventTime.
+const EventTimeType = 0
+
+// EventTime appends t as the forward protocol EventTime extension.
+func (b *Buffer) EventTime(t time.Time) {
+	b.b = append(b.b, 0xd7, EventTimeType)
+	b.b = appendUint32(b.b, uint32(t.Unix()))
+	b.b = appendUint32(b.b, uint32(t.Nanosecond()))
+}
+
+// Value appends a value of the types produced by encoding/json and
+// zapcore.MapObjectEncoder. Map keys are sorted. Other types are
+// appended as their string form.
+func (b *Buffer) Value(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		b.Nil()
+	case bool:
+		b.Bool(v)
+	case string:
+		b.String(v)
+	case []byte:
+		b.Binary(v)
+	case int:
+		b.Int(int64(v))
+	case int8:
+		b.Int(int64(v))
+	case int16:
+		b.Int(int64(v))
+	case int32:
+		b.Int(int64(v))
+	case int64:
+		b.Int(v)
+	case uint:
+		b.Uint(uint64(v))
+	case uint8:
+		b.Uint(uint64(v))
+	case uint16:
+		b.Uint(uint64(v))
+	case uint32:
+		b.Uint(uint64(v))
+	case uint64:
+		b.Uint(v)
+	case float32:
+		b.Float64(float64(v))
+	case float64:
+		b.Float64(v)
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			b.Int(i)
+		} else if f, err := v.Float64(); err == nil {
+			b.Float64(f)
+		} else {
+			b.String(string(v))
+		}
+	case time.Time:
+		b.EventTime(v)
+	case []interface{}:
+		b.ArrayHeader(len(v))
+		for _, item := range v {
+			b.Value(item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.MapHeader(len(keys))
+		for _, key := range keys {
+			b.String(key)
+			b.Value(v[key])
+		}
+	case fmt.Stringer:
+		b.String(v.String())
+	default:
+		b.String(fmt.Sprint(v))
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
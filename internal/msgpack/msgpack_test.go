@@ -0,0 +1,97 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	var b Buffer
+	b.ArrayHeader(3)
+	b.String("tag")
+	b.Int(-1)
+	b.MapHeader(1)
+	b.String("a")
+	b.Uint(200)
+
+	assert.Equal(t, []byte{
+		0x93,
+		0xa3, 't', 'a', 'g',
+		0xff,
+		0x81, 0xa1, 'a', 0xcc, 200,
+	}, b.Bytes())
+}
+
+func TestRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	values := []interface{}{
+		nil,
+		true,
+		false,
+		uint64(0),
+		uint64(127),
+		uint64(128),
+		uint64(70000),
+		uint64(1 << 40),
+		int64(-1),
+		int64(-33),
+		int64(-200),
+		int64(-70000),
+		int64(-1 << 40),
+		1.5,
+		"",
+		long,
+		[]byte{1, 2, 3},
+		[]interface{}{"a", uint64(1)},
+		map[string]interface{}{"k": "v", "n": nil},
+	}
+
+	var b Buffer
+	for _, v := range values {
+		b.Value(v)
+	}
+
+	d := NewDecoder(bytes.NewReader(b.Bytes()))
+	for _, expected := range values {
+		v, err := d.Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+}
+
+func TestBuffer_jsonValues(t *testing.T) {
+	var record map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(`{"level":"info","n":42,"f":0.5,"tags":["x"]}`))
+	dec.UseNumber()
+	assert.NoError(t, dec.Decode(&record))
+
+	var b Buffer
+	b.Value(record)
+
+	v, err := NewDecoder(bytes.NewReader(b.Bytes())).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"level": "info",
+		"n":     uint64(42),
+		"f":     0.5,
+		"tags":  []interface{}{"x"},
+	}, v)
+}
+
+func TestEventTime(t *testing.T) {
+	ts := time.Unix(1600000000, 123)
+
+	var b Buffer
+	b.EventTime(ts)
+
+	v, err := NewDecoder(bytes.NewReader(b.Bytes())).Decode()
+	assert.NoError(t, err)
+	decoded, ok := v.(Ext).Time()
+	assert.True(t, ok)
+	assert.True(t, ts.Equal(decoded))
+}
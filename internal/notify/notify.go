@@ -0,0 +1,108 @@
+// Package notify holds the pieces shared by the appenders sending
+// notifications built from templates: the template data and rate limiting.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a log entry in the template data.
+type Entry struct {
+	Time    time.Time
+	Level   string
+	Logger  string
+	Message string
+	Caller  string
+	Stack   string
+	Fields  map[string]interface{}
+	// Encoded is the entry formatted by the appender encoder.
+	Encoded string
+
+	level zapcore.Level
+}
+
+// NewEntry creates the template entry of a zap entry and its encoded form.
+func NewEntry(ent zapcore.Entry, fields []zapcore.Field, p []byte) *Entry {
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	e := &Entry{
+		Time:    ent.Time,
+		Level:   ent.Level.CapitalString(),
+		Logger:  ent.LoggerName,
+		Message: ent.Message,
+		Stack:   ent.Stack,
+		Fields:  enc.Fields,
+		Encoded: string(bytes.TrimRight(p, "\r\n")),
+		level:   ent.Level,
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	return e
+}
+
+// TextEntry creates the template entry of an encoded entry written without
+// the zap entry.
+func TextEntry(p []byte) *Entry {
+	return &Entry{
+		Time:    time.Now(),
+		Encoded: string(bytes.TrimRight(p, "\r\n")),
+		level:   zapcore.InfoLevel,
+	}
+}
+
+// MaxLevel returns the highest level of the entries.
+func MaxLevel(entries []Entry) zapcore.Level {
+	level := zapcore.DebugLevel
+	for _, e := range entries {
+		if e.level > level {
+			level = e.level
+		}
+	}
+	return level
+}
+
+// Funcs are the functions available in the templates.
+var Funcs = map[string]interface{}{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Limiter allows at most Limit events per fixed Window, 0 means no limit.
+// It is not safe for concurrent use.
+type Limiter struct {
+	Limit  int
+	Window time.Duration
+
+	start time.Time
+	count int
+}
+
+// Allow counts an event and reports whether it is within the limit.
+func (l *Limiter) Allow() bool {
+	if l.Limit == 0 {
+		return true
+	}
+	now := time.Now()
+	if now.Sub(l.start) >= l.Window {
+		l.start = now
+		l.count = 0
+	}
+	if l.count >= l.Limit {
+		return false
+	}
+	l.count++
+	return true
+}
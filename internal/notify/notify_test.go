@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewEntry(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		LoggerName: "db",
+		Message:    "failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/db/conn.go", 10, true),
+	}
+	e := NewEntry(ent, []zapcore.Field{zap.Int("attempt", 3)}, []byte("ERROR failed\n"))
+
+	assert.Equal(t, "ERROR", e.Level)
+	assert.Equal(t, "db/conn.go:10", e.Caller)
+	assert.Equal(t, "ERROR failed", e.Encoded)
+	assert.Equal(t, map[string]interface{}{"attempt": int64(3)}, e.Fields)
+	assert.Equal(t, zapcore.ErrorLevel, MaxLevel([]Entry{*TextEntry(nil), *e}))
+}
+
+func TestLimiter(t *testing.T) {
+	l := Limiter{Limit: 2, Window: 20 * time.Millisecond}
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+
+	time.Sleep(25 * time.Millisecond)
+	assert.True(t, l.Allow())
+
+	unlimited := Limiter{}
+	for i := 0; i < 10; i++ {
+		assert.True(t, unlimited.Allow())
+	}
+}
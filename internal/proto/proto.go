@@ -0,0 +1,150 @@
+// Package proto is a minimal protocol buffers encoder for the few messages
+// the appenders send, so they do not depend on generated code.
+package proto
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// Buffer accumulates an encoded message.
+type Buffer struct {
+	b []byte
+}
+
+// Bytes returns the encoded message.
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+// Reset empties the buffer keeping its memory.
+func (b *Buffer) Reset() {
+	b.b = b.b[:0]
+}
+
+func (b *Buffer) tag(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *Buffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.b = append(b.b, byte(v)|0x80)
+		v >>= 7
+	}
+	b.b = append(b.b, byte(v))
+}
+
+// Uint64 appends a varint field, zero values are omitted.
+func (b *Buffer) Uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	b.varint(v)
+}
+
+// Int64Value appends a varint field even if it is zero, as oneof members
+// and fields with explicit presence require.
+func (b *Buffer) Int64Value(field int, v int64) {
+	b.tag(field, wireVarint)
+	b.varint(uint64(v))
+}
+
+// BoolValue appends a varint field even if it is false.
+func (b *Buffer) BoolValue(field int, v bool) {
+	var u int64
+	if v {
+		u = 1
+	}
+	b.Int64Value(field, u)
+}
+
+// Int64 appends a varint field, zero values are omitted.
+func (b *Buffer) Int64(field int, v int64) {
+	b.Uint64(field, uint64(v))
+}
+
+// Bool appends a varint field, false is omitted.
+func (b *Buffer) Bool(field int, v bool) {
+	if v {
+		b.Uint64(field, 1)
+	}
+}
+
+// Fixed64 appends a fixed64 field, zero values are omitted.
+func (b *Buffer) Fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	b.b = append(b.b, buf[:]...)
+}
+
+// Double appends a double field, zero values are omitted.
+func (b *Buffer) Double(field int, v float64) {
+	b.Fixed64(field, math.Float64bits(v))
+}
+
+// DoubleValue appends a double field even if it is zero.
+func (b *Buffer) DoubleValue(field int, v float64) {
+	b.tag(field, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	b.b = append(b.b, buf[:]...)
+}
+
+// String appends a string field, empty strings are omitted.
+func (b *Buffer) String(field int, v string) {
+	if len(v) == 0 {
+		return
+	}
+	b.StringValue(field, v)
+}
+
+// StringValue appends a string field even if it is empty.
+func (b *Buffer) StringValue(field int, v string) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(v)))
+	b.b = append(b.b, v...)
+}
+
+// BytesField appends a bytes field, empty values are omitted.
+func (b *Buffer) BytesField(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(v)))
+	b.b = append(b.b, v...)
+}
+
+// Message appends an embedded message written by fn. Empty messages are
+// still written, as their presence may matter.
+func (b *Buffer) Message(field int, fn func(b *Buffer)) {
+	b.tag(field, wireBytes)
+
+	// reserve one byte for the length, most messages are short
+	start := len(b.b) + 1
+	b.b = append(b.b, 0)
+	fn(b)
+
+	size := uint64(len(b.b) - start)
+	if size < 0x80 {
+		b.b[start-1] = byte(size)
+		return
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], size)
+	b.b = append(b.b, prefix[:n-1]...)
+	copy(b.b[start+n-1:], b.b[start:start+int(size)])
+	copy(b.b[start-1:], prefix[:n])
+}
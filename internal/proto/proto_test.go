@@ -0,0 +1,56 @@
+package proto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	var b Buffer
+	b.Uint64(1, 150)
+	b.String(2, "testing")
+	b.Int64(3, 0)
+	b.Message(4, func(b *Buffer) {
+		b.Uint64(1, 150)
+	})
+	b.Bool(5, true)
+	b.Double(6, 1)
+
+	assert.Equal(t, []byte{
+		0x08, 0x96, 0x01,
+		0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g',
+		0x22, 0x03, 0x08, 0x96, 0x01,
+		0x28, 0x01,
+		0x31, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f,
+	}, b.Bytes())
+}
+
+func TestBuffer_longMessage(t *testing.T) {
+	long := strings.Repeat("x", 300)
+
+	var b Buffer
+	b.Message(1, func(b *Buffer) {
+		b.String(1, long)
+	})
+
+	// 300 bytes of payload plus the field header of 3 bytes
+	expected := append([]byte{0x0a, 0xaf, 0x02, 0x0a, 0xac, 0x02}, long...)
+	assert.Equal(t, expected, b.Bytes())
+}
+
+func TestBuffer_values(t *testing.T) {
+	var b Buffer
+	b.Int64Value(1, 0)
+	b.BoolValue(2, false)
+	b.DoubleValue(3, 0)
+	b.StringValue(4, "")
+
+	assert.Equal(t, []byte{
+		0x08, 0x00,
+		0x10, 0x00,
+		0x19, 0, 0, 0, 0, 0, 0, 0, 0,
+		0x22, 0x00,
+	}, b.Bytes())
+}
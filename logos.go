@@ -3,6 +3,7 @@ package logos
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
@@ -124,7 +125,7 @@ func init() {
 	manager.RedirectStdLog()
 
 	go func() {
-		quit := make(chan os.Signal)
+		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 		<-quit
 		Sync()
@@ -204,6 +205,52 @@ func Sync() {
 	_ = manager.Sync()
 }
 
+// Dump writes the entries kept by the appender with the given name,
+// e.g. a memory appender, to w.
+func Dump(appenderName string, w io.Writer) error {
+	return manager.Dump(appenderName, w)
+}
+
+// Rotate starts new files for the appenders with the given names, e.g.
+// rolling_file appenders, or for every appender supporting rotation if no
+// name is given.
+func Rotate(appenderName ...string) error {
+	return manager.Rotate(appenderName...)
+}
+
+// RotateOnSignal rotates every appender supporting rotation when one of
+// the signals, SIGHUP by default, is received. The returned function
+// stops handling the signals.
+func RotateOnSignal(sig ...os.Signal) func() {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, sig...)
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := Rotate(); err != nil {
+					debugf("rotating appenders error: %s\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
 func RedirectStdLog() func() {
 	return manager.RedirectStdLog()
 }
@@ -1,6 +1,8 @@
 package logos
 
 import (
+	"fmt"
+	"io"
 	log2 "log"
 	"sync"
 
@@ -30,10 +32,10 @@ type logManager struct {
 	cancelRedirectStdLog func()
 }
 
-func newLogManager(rawConfig *common.Config) (*logManager, error) {
+func newLogManager(rawConfig *common.Config) (_ *logManager, err error) {
 
 	config := config2.Config{}
-	err := rawConfig.Unpack(&config)
+	err = rawConfig.Unpack(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -44,12 +46,16 @@ func newLogManager(rawConfig *common.Config) (*logManager, error) {
 		appenders:     map[string]*appender.Appender{},
 	}
 
+	// The appenders own connections, goroutines and signal handlers,
+	// a rejected config must not leak them.
+	defer func() {
+		if err != nil {
+			closeAppenders(m.appenders)
+		}
+	}()
+
 	for appenderType, appenderConfigs := range config.Appenders {
 		for _, appenderConfig := range appenderConfigs {
-			createAppender, err := appender.CreateAppender(appenderType, appenderConfig)
-			if err != nil {
-				return nil, err
-			}
 			name, err := appenderConfig.Name()
 			if err != nil {
 				return nil, err
@@ -60,10 +66,19 @@ func newLogManager(rawConfig *common.Config) (*logManager, error) {
 				continue
 			}
 
+			createAppender, err := appender.CreateAppender(appenderType, appenderConfig)
+			if err != nil {
+				return nil, err
+			}
+
 			m.appenders[name] = createAppender
 		}
 	}
 
+	if err := appender.LinkFallbacks(m.appenders); err != nil {
+		return nil, err
+	}
+
 	err = m.newRootLoggerFromCfg(config.Loggers.Root)
 
 	if err != nil {
@@ -292,12 +307,7 @@ func (m *logManager) Update(rawConfig *common.Config) error {
 	m.getLoggerLocker.Lock()
 	defer m.getLoggerLocker.Unlock()
 
-	err = m.Sync()
-
-	if err != nil {
-		return err
-	}
-
+	oldAppenders := m.appenders
 	m.appenders = nc.appenders
 	m.rootLevel = nc.rootLevel
 	m.rootLoggerConfig = nc.rootLoggerConfig
@@ -330,9 +340,79 @@ func (m *logManager) Update(rawConfig *common.Config) error {
 		m.cancelRedirectStdLog = m.RedirectStdLog()
 	}
 
+	// The loggers write to the new appenders from now on, the entries in
+	// flight to the old ones are flushed by closing them. The writes
+	// arriving after that fail without reopening their outputs.
+	closeAppenders(oldAppenders)
+
 	return nil
 }
 
+func closeAppenders(appenders map[string]*appender.Appender) {
+	for name, a := range appenders {
+		_ = a.Writer.Sync()
+		if c, ok := a.Writer.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				debugf("closing appender <%s> error: %s\n", name, err)
+			}
+		}
+	}
+}
+
+func (m *logManager) Dump(name string, w io.Writer) error {
+
+	m.getLoggerLocker.RLock()
+	a, ok := m.appenders[name]
+	m.getLoggerLocker.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAppenderNotFound, name)
+	}
+
+	d, ok := a.Writer.(appender.Dumper)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDumpNotSupported, name)
+	}
+
+	return d.Dump(w)
+}
+
+// Rotate rotates the named appenders, or every appender supporting it if
+// no name is given. It rotates all of them and returns the first error.
+func (m *logManager) Rotate(names ...string) error {
+
+	m.getLoggerLocker.RLock()
+	defer m.getLoggerLocker.RUnlock()
+
+	var rotators []appender.Rotator
+	if len(names) == 0 {
+		for _, a := range m.appenders {
+			if r, ok := a.Writer.(appender.Rotator); ok {
+				rotators = append(rotators, r)
+			}
+		}
+	}
+	for _, name := range names {
+		a, ok := m.appenders[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrAppenderNotFound, name)
+		}
+		r, ok := a.Writer.(appender.Rotator)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrRotateNotSupported, name)
+		}
+		rotators = append(rotators, r)
+	}
+
+	var firstErr error
+	for _, r := range rotators {
+		if err := r.Rotate(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 func (m *logManager) Sync() error {
 	m.coreLoggers.Range(func(_, value interface{}) bool {
 		_ = value.(*warpLogger).Sync()
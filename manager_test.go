@@ -0,0 +1,145 @@
+package logos
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/khorevaa/logos/appender"
+	"github.com/khorevaa/logos/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// closerWriters records the writers of the closer_test type by name.
+var closerWriters = struct {
+	sync.Mutex
+	created map[string][]*closerWriter
+}{created: map[string][]*closerWriter{}}
+
+type closerWriter struct {
+	mu     sync.Mutex
+	synced bool
+	closed bool
+	writes int
+}
+
+func (w *closerWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return len(p), nil
+}
+
+func (w *closerWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.synced = true
+	return nil
+}
+
+func (w *closerWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func init() {
+	appender.RegisterWriterType("closer_test", func(config *common.Config) (zapcore.WriteSyncer, error) {
+		name, err := config.Name()
+		if err != nil {
+			return nil, err
+		}
+		w := &closerWriter{}
+		closerWriters.Lock()
+		closerWriters.created[name] = append(closerWriters.created[name], w)
+		closerWriters.Unlock()
+		return w, nil
+	})
+}
+
+func lastCloserWriter(name string) *closerWriter {
+	closerWriters.Lock()
+	defer closerWriters.Unlock()
+	created := closerWriters.created[name]
+	if len(created) == 0 {
+		return nil
+	}
+	return created[len(created)-1]
+}
+
+func TestNewLogManager_closesAppendersOnError(t *testing.T) {
+	cfg, err := common.NewConfigFrom(`
+appenders:
+  closer_test:
+    - name: LEAK_A
+      encoder:
+        json:
+    - name: LEAK_B
+      encoder:
+        json:
+loggers:
+  root:
+    level: loud
+    appender_refs:
+      - LEAK_A
+`)
+	assert.NoError(t, err)
+
+	_, err = newLogManager(cfg)
+	assert.Error(t, err)
+	for _, name := range []string{"LEAK_A", "LEAK_B"} {
+		w := lastCloserWriter(name)
+		if assert.NotNil(t, w, name) {
+			assert.True(t, w.closed, name)
+		}
+	}
+}
+
+func TestLogManager_Update(t *testing.T) {
+	const config = `
+appenders:
+  closer_test:
+    - name: RELOADED
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - RELOADED
+`
+	m, err := newLogManager(common.MustNewConfigFrom(config))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	old := lastCloserWriter("RELOADED")
+	log := m.NewLogger("update")
+	log.Info("before")
+
+	assert.NoError(t, m.Update(common.MustNewConfigFrom(config)))
+	current := lastCloserWriter("RELOADED")
+	assert.NotSame(t, old, current)
+	assert.True(t, old.synced)
+	assert.True(t, old.closed)
+	assert.False(t, current.closed)
+
+	log.Info("after")
+	assert.Equal(t, 1, old.writes)
+	assert.Equal(t, 1, current.writes)
+
+	// a rejected config keeps the current appenders
+	assert.Error(t, m.Update(common.MustNewConfigFrom(`
+appenders:
+  closer_test:
+    - name: RELOADED
+      encoder:
+        json:
+loggers:
+  root:
+    level: loud
+`)))
+	assert.True(t, lastCloserWriter("RELOADED").closed)
+	assert.False(t, current.closed)
+	closeAppenders(m.appenders)
+}
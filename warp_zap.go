@@ -15,7 +15,7 @@ func newZapCore(config map[string]zap.AtomicLevel, appenders map[string]*appende
 	for name, level := range config {
 
 		if a, ok := appenders[name]; ok {
-			zcs = append(zcs, zapcore.NewCore(a.Encoder, a.Writer, level))
+			zcs = append(zcs, a.NewCore(level))
 		}
 
 	}